@@ -13,7 +13,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/adrg/xdg"
 	"github.com/choria-io/fisk"
@@ -103,6 +106,23 @@ type AppBuilder struct {
 	stdErr         io.Writer
 	log            Logger
 	exitWithUsage  bool
+	interruptGrace time.Duration
+	// shutdowns are commands still terminating their processes after an interrupt
+	shutdowns sync.WaitGroup
+	// allowUnknownKeys disables strict decoding of definitions, set from the definition
+	allowUnknownKeys bool
+	// deferUnknownKeys loads definitions with unknown keys so validate can report them with their locations
+	deferUnknownKeys bool
+	// env selects the environment specific overlay to apply to definitions
+	env          string
+	renderPath   []string
+	renderFormat string
+	// includeCache holds copies of remote includes named by their checksum
+	includeCache string
+	// completionCache holds the output of completion commands
+	completionCache string
+	// inheritedFlags are the flags of the application and parents added to the commands being created or validated
+	inheritedFlags []inheritedFlag
 }
 
 var (
@@ -123,6 +143,8 @@ var (
 		"ABTaskFile",
 	}
 
+	defaultInterruptGrace = 2 * time.Second
+
 	requireDescription   = true
 	appDefPattern        = "%s-app.yaml"
 	appCfgPatten         = "%s-cfg.yaml"
@@ -137,12 +159,16 @@ Contact: %s
 // New creates a new CLI Builder
 func New(ctx context.Context, name string, opts ...Option) (*AppBuilder, error) {
 	builder := &AppBuilder{
-		cfg:    make(map[string]any),
-		ctx:    ctx,
-		name:   name,
-		stdOut: os.Stdout,
-		stdErr: os.Stderr,
-		log:    NewDefaultLogger(),
+		cfg:             make(map[string]any),
+		ctx:             ctx,
+		name:            name,
+		stdOut:          os.Stdout,
+		stdErr:          os.Stderr,
+		log:             NewDefaultLogger(),
+		interruptGrace:  defaultInterruptGrace,
+		env:             os.Getenv("BUILDER_ENV"),
+		includeCache:    filepath.Join(xdg.CacheHome, "appbuilder", "includes"),
+		completionCache: filepath.Join(xdg.CacheHome, "appbuilder", "completions"),
 		cfgSources: []string{
 			filepath.Join(xdg.ConfigHome, "appbuilder"),
 			"/etc/appbuilder",
@@ -182,6 +208,14 @@ func (b *AppBuilder) DefinitionDirectory() string {
 	return filepath.Dir(b.definitionPath)
 }
 
+// DelayInterruptExit keeps the application from exiting after an interrupt until the returned function is called,
+// commands use this while terminating the processes they started
+func (b *AppBuilder) DelayInterruptExit() func() {
+	b.shutdowns.Add(1)
+
+	return b.shutdowns.Done
+}
+
 // Stdout is the target for writing errors
 func (b *AppBuilder) Stdout() io.Writer {
 	return b.stdOut
@@ -216,8 +250,21 @@ func (b *AppBuilder) CreateBuilderApp(cmd KingpinCommand) {
 	validate := cmd.Command("validate", "Validates a application definition").Action(b.validateAction)
 	validate.Arg("definition", "Path to the definition to validate").Required().ExistingFileVar(&b.appPath)
 
-	cmd.Command("info", "Shows information about the App Builder setup").Action(b.infoAction)
+	lint := cmd.Command("lint", "Finds likely mistakes in a valid application definition").Action(b.lintAction)
+	lint.Arg("definition", "Path to the definition to lint").Required().ExistingFileVar(&b.appPath)
+
+	render := cmd.Command("render", "Shows a application definition with all includes, overlays and templates expanded").Action(b.renderAction)
+	render.Arg("definition", "Path to the definition to render").Required().ExistingFileVar(&b.appPath)
+	render.Arg("command", "Only render the command found by following these command names").StringsVar(&b.renderPath)
+	render.Flag("format", "The format to render the definition in").Default("yaml").EnumVar(&b.renderFormat, "yaml", "json")
+
+	update := cmd.Command("update-includes", "Fetches remote includes and pins their current checksums").Action(b.updateIncludesAction)
+	update.Arg("definition", "Path to the definition to update").Required().ExistingFileVar(&b.appPath)
+
+	info := cmd.Command("info", "Shows information about the App Builder setup").Action(b.infoAction)
+	info.Arg("definition", "Path to a definition to show the overlays applied to").ExistingFileVar(&b.appPath)
 	cmd.Command("list", "List applications").Action(b.listAction)
+	cmd.Command("schema", "Shows the JSON Schema for application definitions").Action(b.schemaAction)
 }
 
 // RunBuilderCLI runs the builder command, used to validate apps and more
@@ -304,6 +351,17 @@ func (b *AppBuilder) listAction(_ *fisk.ParseContext) error {
 	return nil
 }
 
+func (b *AppBuilder) schemaAction(_ *fisk.ParseContext) error {
+	j, err := json.MarshalIndent(Schema(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(b.stdOut, string(j))
+
+	return nil
+}
+
 func (b *AppBuilder) infoAction(_ *fisk.ParseContext) error {
 	fmt.Println("Choria Application Builder")
 	fmt.Println()
@@ -319,42 +377,137 @@ func (b *AppBuilder) infoAction(_ *fisk.ParseContext) error {
 		fmt.Printf("        Definition File (BUILDER_APP): not specified\n")
 	}
 
+	if b.env != "" {
+		fmt.Printf("            Environment (BUILDER_ENV): %s\n", b.env)
+	} else {
+		fmt.Printf("            Environment (BUILDER_ENV): not specified\n")
+	}
+
 	fmt.Printf("                     Source Locations: %s\n", strings.Join(b.cfgSources, ", "))
 
+	if b.appPath == "" {
+		return nil
+	}
+
+	b.deferUnknownKeys = true
+	d, err := b.LoadDefinition()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("                           Definition: %s\n", b.definitionPath)
+	if len(d.overlays) == 0 {
+		fmt.Printf("                     Applied Overlays: none\n")
+	}
+	for i, overlay := range d.overlays {
+		if i == 0 {
+			fmt.Printf("                     Applied Overlays: %s\n", overlay)
+		} else {
+			fmt.Printf("                                       %s\n", overlay)
+		}
+	}
+
 	return nil
 }
 
 func (b *AppBuilder) validateAction(_ *fisk.ParseContext) error {
+	b.deferUnknownKeys = true
+
 	d, err := b.LoadDefinition()
 	if err != nil {
 		return err
 	}
 
-	errs := make(chan string, 10000)
+	v := newDefinitionValidator(b)
+	v.validateDefinition(d)
 
-	err = d.Validate(nil)
-	if err != nil {
-		errs <- err.Error()
+	if len(v.errs) > 0 {
+		fmt.Printf("Application definition %s not valid:\n", b.appPath)
+		fmt.Println()
+		for _, e := range v.errs {
+			fmt.Println(e)
+		}
+
+		os.Exit(1)
 	}
 
-	b.validateCommands([]string{"root"}, errs, d.commands...)
+	fmt.Printf("Application definition %s is valid\n", b.appPath)
 
-	close(errs)
+	return nil
+}
 
-	if len(errs) > 0 {
-		fmt.Printf("Application definition %s not valid:\n", b.appPath)
+func (b *AppBuilder) lintAction(_ *fisk.ParseContext) error {
+	b.deferUnknownKeys = true
+
+	d, err := b.LoadDefinition()
+	if err != nil {
+		return err
+	}
+
+	l := newDefinitionLinter(b, d)
+	l.lintDefinition(d)
+
+	if len(l.problems()) > 0 {
+		fmt.Printf("Application definition %s has problems:\n", b.appPath)
 		fmt.Println()
-		for e := range errs {
-			fmt.Printf("   %v\n", e)
+		for _, p := range l.problems() {
+			fmt.Println(p)
 		}
 
 		os.Exit(1)
-	} else {
-		fmt.Printf("Application definition %s is valid\n", b.appPath)
+	}
+
+	fmt.Printf("Application definition %s has no problems\n", b.appPath)
+
+	return nil
+}
+
+func (b *AppBuilder) renderAction(_ *fisk.ParseContext) error {
+	b.deferUnknownKeys = true
+
+	d, err := b.LoadDefinition()
+	if err != nil {
+		return err
+	}
+
+	out, err := renderDefinition(d, b.renderPath, b.renderFormat)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(b.stdOut, strings.TrimSpace(string(out)))
+
+	return nil
+}
+
+func (b *AppBuilder) updateIncludesAction(_ *fisk.ParseContext) error {
+	updates, err := b.updateIncludes(b.appPath)
+	if err != nil {
+		return err
+	}
+
+	if len(updates) == 0 {
+		fmt.Fprintf(b.stdOut, "Application definition %s has no remote includes\n", b.appPath)
 		return nil
 	}
 
-	return err
+	for _, u := range updates {
+		switch {
+		case u.include.SHA256 == u.sha256:
+			fmt.Fprintf(b.stdOut, "%s: %s is up to date\n", u.file, u.include.File)
+		case u.remote:
+			fmt.Fprintf(b.stdOut, "%s: %s has checksum %s, the remote file needs to be updated\n", u.file, u.include.File, u.sha256)
+		case u.pinned:
+			fmt.Fprintf(b.stdOut, "%s: %s pinned to %s\n", u.file, u.include.File, u.sha256)
+		case u.include.SHA256 == "":
+			fmt.Fprintf(b.stdOut, "%s: %s could not be pinned, add sha256: %s\n", u.file, u.include.File, u.sha256)
+		default:
+			fmt.Fprintf(b.stdOut, "%s: %s updated to %s\n", u.file, u.include.File, u.sha256)
+		}
+	}
+
+	return nil
 }
 
 // HasDefinition determines if the named definition can be found on the node
@@ -384,36 +537,64 @@ func (b *AppBuilder) loadDefinitionBytes(cfg []byte, path string) (*Definition,
 		return nil, fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
 	}
 
-	if d.IncludeFile != "" {
-		f, err := os.ReadFile(d.IncludeFile)
-		if err != nil {
-			return nil, err
-		}
-		cfgj, err := yaml.YAMLToJSON(f)
+	d.unknownKeys, err = findUnknownKeys(cfgj, reflect.TypeOf(d))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+
+	e := newIncludeExpander(b)
+	if abs, err := filepath.Abs(path); err == nil {
+		e.stack = append(e.stack, abs)
+	}
+
+	cmds, err := e.expandDefinition(d, path, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if fileExist(path) {
+		overlays, err := overlayFiles(path, b.env)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
 		}
 
-		def := &Definition{}
-		err = json.Unmarshal(cfgj, def)
+		for _, overlay := range overlays {
+			if b.log != nil {
+				b.log.Debugf("Applying overlay %s", overlay)
+			}
+
+			cmds, err = e.applyOverlay(d, cmds, overlay)
+			if err != nil {
+				return nil, err
+			}
+			d.overlays = append(d.overlays, overlay)
+		}
+	}
+	d.unknownKeys = append(d.unknownKeys, e.unknown...)
+
+	err = e.expandTemplates(d.Templates, cmds, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d.origins = map[string]commandOrigin{}
+	collectOrigins(cmds, "$.commands", d.origins)
+
+	d.Commands = nil
+	for _, cmd := range cmds {
+		j, err := json.Marshal(cmd)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
 		}
+		d.Commands = append(d.Commands, j)
+	}
 
-		if d.Name != "" {
-			def.Name = d.Name
-		}
-		if d.Author != "" {
-			def.Author = d.Author
-		}
-		if d.Description != "" {
-			def.Description = d.Description
-		}
-		if d.Version != "" {
-			def.Version = d.Version
+	b.allowUnknownKeys = d.AllowUnknownKeys
+	if !b.allowUnknownKeys && !b.deferUnknownKeys {
+		err = unknownKeysError(d.unknownKeys)
+		if err != nil {
+			return nil, err
 		}
-
-		d = def
 	}
 
 	err = b.createCommands(d, d.Commands)
@@ -576,11 +757,13 @@ func (b *AppBuilder) createAppCLI() (*fisk.Application, error) {
 		cmd.Help = fmt.Sprintf("%s\n\nUse '%s cheat' to access cheat sheet style help", cmd.Help, b.name)
 	}
 
-	err := b.registerCommands(cmd, b.def.commands...)
+	err := b.registerDefinition(cmd)
 	if err != nil {
 		return nil, err
 	}
 
+	b.addCompletionCommand(cmd)
+
 	return cmd, nil
 }
 
@@ -705,33 +888,26 @@ func (b *AppBuilder) findConfigFile(name string, override string) (string, error
 	return source, nil
 }
 
-func (b *AppBuilder) validateCommands(bread []string, errs chan string, cmds ...Command) {
-	for _, c := range cmds {
-		bread = append(bread, c.String())
-		b.log.Debugf("Validating %s", c)
-		err := c.Validate(b.log)
-		if err != nil {
-			errs <- fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), err)
-		}
-
-		for _, sub := range c.SubCommands() {
-			sc, err := b.createCommand(sub)
-			if err != nil {
-				errs <- fmt.Sprintf("%s: %s", strings.Join(bread, " ->  "), err.Error())
-			}
-
-			b.validateCommands(bread, errs, sc)
-		}
-	}
+// registerDefinition registers all commands of the definition, the flags of the definition are inherited by all commands
+func (b *AppBuilder) registerDefinition(cli KingpinCommand) error {
+	return b.withInheritedFlags("the application", b.def.Flags, func() error {
+		return b.registerCommands(cli, b.def.Commands, b.def.commands...)
+	})
 }
 
-func (b *AppBuilder) registerCommands(cli KingpinCommand, cmds ...Command) error {
+// registerCommands registers cmds, defined by raw, and all their sub commands
+func (b *AppBuilder) registerCommands(cli KingpinCommand, raw []json.RawMessage, cmds ...Command) error {
 	bread := []string{"root"}
 
-	for _, c := range cmds {
+	for i, c := range cmds {
 		bread = append(bread, c.String())
 		b.log.Debugf("Registering %s", c)
 		err := c.Validate(b.log)
+		if err == nil && i < len(raw) {
+			if errs := b.inheritedFlagErrors(raw[i]); len(errs) > 0 {
+				err = errors.New(strings.Join(errs, ", "))
+			}
+		}
 		if err != nil {
 			return fmt.Errorf("%w: %s: %s", ErrInvalidDefinition, strings.Join(bread, " -> "), err)
 		}
@@ -743,16 +919,23 @@ func (b *AppBuilder) registerCommands(cli KingpinCommand, cmds ...Command) error
 
 		subs := c.SubCommands()
 		if len(subs) > 0 {
-			for _, sub := range subs {
-				subCommand, err := b.createCommand(sub)
-				if err != nil {
-					return err
+			err = b.withCommandFlags(c, func() error {
+				for _, sub := range subs {
+					subCommand, err := b.createCommand(sub)
+					if err != nil {
+						return err
+					}
+
+					err = b.registerCommands(cmd, []json.RawMessage{sub}, subCommand)
+					if err != nil {
+						return err
+					}
 				}
 
-				err = b.registerCommands(cmd, subCommand)
-				if err != nil {
-					return err
-				}
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -19,8 +19,8 @@ import (
 )
 
 func RunTaskCLI(ctx context.Context, watchInterrupts bool, opts ...Option) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
 	if opts == nil {
 		opts = []Option{}
@@ -32,7 +32,7 @@ func RunTaskCLI(ctx context.Context, watchInterrupts bool, opts ...Option) error
 	}
 
 	if watchInterrupts {
-		go interruptWatcher(ctx, cancel, bldr.log)
+		go interruptWatcher(ctx, cancel, bldr)
 	}
 
 	requireDescription = false
@@ -53,8 +53,8 @@ Help: https://choria-io.github.io/appbuilder`
 }
 
 func RunBuilderCLI(ctx context.Context, watchInterrupts bool, opts ...Option) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
 	if opts == nil {
 		opts = []Option{}
@@ -66,7 +66,7 @@ func RunBuilderCLI(ctx context.Context, watchInterrupts bool, opts ...Option) er
 	}
 
 	if watchInterrupts {
-		go interruptWatcher(ctx, cancel, bldr.log)
+		go interruptWatcher(ctx, cancel, bldr)
 	}
 
 	return bldr.RunBuilderCLI()
@@ -84,13 +84,13 @@ func MountAsCommand(ctx context.Context, app KingpinCommand, definition []byte,
 		return err
 	}
 
-	return bldr.registerCommands(app, bldr.def.commands...)
+	return bldr.registerDefinition(app)
 }
 
 // RunStandardCLI runs a standard command line instance with shutdown watchers etc. If log is nil a logger will be created
 func RunStandardCLI(ctx context.Context, name string, watchInterrupts bool, log Logger, opts ...Option) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
 	if opts == nil {
 		opts = []Option{}
@@ -102,7 +102,7 @@ func RunStandardCLI(ctx context.Context, name string, watchInterrupts bool, log
 	}
 
 	if watchInterrupts {
-		go interruptWatcher(ctx, cancel, bldr.log)
+		go interruptWatcher(ctx, cancel, bldr)
 	}
 
 	if !bldr.HasDefinition() {
@@ -132,6 +132,16 @@ func createBuilder(ctx context.Context, name string, log Logger, opts ...Option)
 		}
 	}
 
+	if grace := os.Getenv("BUILDER_INTERRUPT_GRACE"); grace != "" {
+		d, err := time.ParseDuration(grace)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BUILDER_INTERRUPT_GRACE: %w", err)
+		}
+
+		// first so that a WithInterruptGrace() option passed by the caller wins
+		opts = append([]Option{WithInterruptGrace(d)}, opts...)
+	}
+
 	// we set the logger option first, if we made a new logger above
 	// it will be set, if the user supplied one, it will be set
 	//
@@ -144,7 +154,26 @@ func createBuilder(ctx context.Context, name string, log Logger, opts ...Option)
 	return New(ctx, name, opts...)
 }
 
-func interruptWatcher(ctx context.Context, cancel context.CancelFunc, log Logger) {
+// interruptError is the cancellation cause recorded when a signal interrupts the application
+type interruptError struct {
+	signal os.Signal
+}
+
+func (e *interruptError) Error() string {
+	return fmt.Sprintf("interrupted by %s", e.signal)
+}
+
+// InterruptSignal is the signal that caused ctx to be cancelled, nil when it was not cancelled by a signal
+func InterruptSignal(ctx context.Context) os.Signal {
+	var ie *interruptError
+	if errors.As(context.Cause(ctx), &ie) {
+		return ie.signal
+	}
+
+	return nil
+}
+
+func interruptWatcher(ctx context.Context, cancel context.CancelCauseFunc, b *AppBuilder) {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
@@ -154,13 +183,15 @@ func interruptWatcher(ctx context.Context, cancel context.CancelFunc, log Logger
 			switch sig {
 			case syscall.SIGINT, syscall.SIGTERM:
 				go func() {
-					<-time.After(2 * time.Second)
+					<-time.After(b.interruptGrace)
+					// commands terminating their processes are bounded by their own grace periods
+					b.shutdowns.Wait()
 					os.Exit(1)
 				}()
 			}
 
-			log.Infof("Shutting down on %s", sig)
-			cancel()
+			b.log.Infof("Shutting down on %s", sig)
+			cancel(&interruptError{signal: sig})
 
 		case <-ctx.Done():
 			return
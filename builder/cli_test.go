@@ -0,0 +1,49 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"context"
+	"errors"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CLI", func() {
+	Describe("InterruptSignal", func() {
+		It("Should report the signal that cancelled the context", func() {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			Expect(InterruptSignal(ctx)).To(BeNil())
+
+			cancel(&interruptError{signal: syscall.SIGINT})
+			Expect(InterruptSignal(ctx)).To(Equal(syscall.SIGINT))
+		})
+
+		It("Should ignore other causes", func() {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cancel(errors.New("other"))
+			Expect(InterruptSignal(ctx)).To(BeNil())
+		})
+	})
+
+	Describe("DelayInterruptExit", func() {
+		It("Should delay the exit until released", func() {
+			b := &AppBuilder{}
+			release := b.DelayInterruptExit()
+
+			exited := make(chan struct{})
+			go func() {
+				b.shutdowns.Wait()
+				close(exited)
+			}()
+
+			Consistently(exited, "100ms").ShouldNot(BeClosed())
+			release()
+			Eventually(exited).Should(BeClosed())
+		})
+	})
+})
@@ -0,0 +1,251 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CommandTemplate is a command defined once and used by many commands with different parameters
+type CommandTemplate struct {
+	// Description describes the template
+	Description string `json:"description"`
+	// Parameters are the parameters the template accepts using with
+	Parameters []CommandTemplateParameter `json:"parameters"`
+	// Command is the command, parameters are referenced as ((name)) in any string
+	Command map[string]any `json:"command"`
+}
+
+// CommandTemplateParameter is a parameter accepted by a command template
+type CommandTemplateParameter struct {
+	// Name is the name of the parameter
+	Name string `json:"name"`
+	// Description describes the parameter
+	Description string `json:"description"`
+	// Default is the value used when the parameter is not given, parameters without a default are required
+	Default *string `json:"default"`
+}
+
+// templateParameterPattern matches ((name)) without spaces so bash arithmetic like (( count > 1 )) is kept, matches
+// starting with $ are bash arithmetic like $((count)) and are also kept
+var templateParameterPattern = regexp.MustCompile(`\$?\(\(([a-zA-Z_][a-zA-Z0-9_-]*)\)\)`)
+
+// expandTemplates replaces commands with a use setting, at any depth in cmds, with the template they use. Settings
+// of the command other than use and with are merged onto the template.
+func (e *includeExpander) expandTemplates(templates map[string]*CommandTemplate, cmds []any, stack []string) error {
+	for i, cmd := range cmds {
+		m, ok := cmd.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if _, ok := m["use"]; ok {
+			expanded, err := e.useTemplate(templates, m, stack)
+			if err != nil {
+				return err
+			}
+			cmds[i] = expanded
+			m = expanded
+		}
+
+		subs, _ := m["commands"].([]any)
+		err := e.expandTemplates(templates, subs, stack)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// useTemplate creates the command cmd by instantiating the template it uses
+func (e *includeExpander) useTemplate(templates map[string]*CommandTemplate, cmd map[string]any, stack []string) (map[string]any, error) {
+	// commands from templates have no origin, errors for them are shown without a location
+	fail := func(format string, a ...any) error {
+		if o, ok := cmd[originKey].(commandOrigin); ok {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidDefinition, e.location(o.file, o.path), fmt.Sprintf(format, a...))
+		}
+
+		return fmt.Errorf("%w: %s", ErrInvalidDefinition, fmt.Sprintf(format, a...))
+	}
+
+	name, ok := cmd["use"].(string)
+	if !ok {
+		return nil, fail("use must be the name of a template")
+	}
+
+	tmpl, ok := templates[name]
+	if !ok {
+		return nil, fail("unknown template %q", name)
+	}
+
+	for _, s := range stack {
+		if s == name {
+			return nil, fail("template cycle %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+
+	with := map[string]any{}
+	if w, ok := cmd["with"]; ok {
+		with, ok = w.(map[string]any)
+		if !ok {
+			return nil, fail("with must be a map of parameters")
+		}
+	}
+
+	if tmpl.Command == nil {
+		return nil, fail("template %s has no command", name)
+	}
+
+	params, err := tmpl.parameters(with)
+	if err != nil {
+		return nil, fail("template %s: %v", name, err)
+	}
+
+	body, err := deepCopy(tmpl.Command)
+	if err != nil {
+		return nil, fail("template %s: %v", name, err)
+	}
+
+	expanded, err := substituteParameters(body, params)
+	if err != nil {
+		return nil, fail("template %s: %v", name, err)
+	}
+	res := expanded.(map[string]any)
+
+	settings := map[string]any{}
+	for k, v := range cmd {
+		if k != "use" && k != "with" {
+			settings[k] = v
+		}
+	}
+	mergeSettings(res, settings)
+
+	if o, ok := cmd[originKey]; ok {
+		res[originKey] = o
+	}
+
+	stack = append(append([]string{}, stack...), name)
+
+	// templates may themselves use templates
+	if _, ok := res["use"]; ok {
+		return e.useTemplate(templates, res, stack)
+	}
+
+	subs, _ := res["commands"].([]any)
+	err = e.expandTemplates(templates, subs, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// parameters are the values for all parameters of t based on those given in with and the defaults
+func (t *CommandTemplate) parameters(with map[string]any) (map[string]string, error) {
+	params := map[string]string{}
+	var errs []string
+
+	for _, p := range t.Parameters {
+		v, ok := with[p.Name]
+		switch {
+		case ok:
+			params[p.Name] = fmt.Sprint(v)
+		case p.Default != nil:
+			params[p.Name] = *p.Default
+		default:
+			errs = append(errs, fmt.Sprintf("parameter %q is required", p.Name))
+		}
+	}
+
+	var names []string
+	for k := range with {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		if !t.hasParameter(k) {
+			errs = append(errs, fmt.Sprintf("unknown parameter %q", k))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+
+	return params, nil
+}
+
+func (t *CommandTemplate) hasParameter(name string) bool {
+	for _, p := range t.Parameters {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// substituteParameters replaces ((name)) in all strings found in v with the parameter values
+func substituteParameters(v any, params map[string]string) (any, error) {
+	switch val := v.(type) {
+	case string:
+		var err error
+		res := templateParameterPattern.ReplaceAllStringFunc(val, func(match string) string {
+			if strings.HasPrefix(match, "$") {
+				return match
+			}
+
+			name := templateParameterPattern.FindStringSubmatch(match)[1]
+			p, ok := params[name]
+			if !ok {
+				if err == nil {
+					err = fmt.Errorf("undeclared parameter %q", name)
+				}
+				return match
+			}
+			return p
+		})
+
+		return res, err
+
+	case []any:
+		for i, item := range val {
+			res, err := substituteParameters(item, params)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = res
+		}
+
+	case map[string]any:
+		for k, item := range val {
+			res, err := substituteParameters(item, params)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = res
+		}
+	}
+
+	return v, nil
+}
+
+func deepCopy(m map[string]any) (any, error) {
+	j, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var res any
+	err = json.Unmarshal(j, &res)
+
+	return res, err
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"fmt"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+var _ = Describe("Command templates", func() {
+	var (
+		td   string
+		load func(string) (*Definition, error)
+	)
+
+	const templates = `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+templates:
+  restart-service:
+    description: Restarts a service
+    parameters:
+      - name: service
+      - name: signal
+        default: HUP
+    command:
+      description: Restarts ((service))
+      type: test
+      banner: |
+        kill -((signal)) $(cat /run/((service)).pid) $((1 + 1))
+        if (( x > 1 )); then (( signal )); ((i++)); fi
+      cheat:
+        label: ((service))
+        cheat: restart ((service))
+  group:
+    command:
+      type: test
+      commands:
+        - name: api
+          use: restart-service
+          with:
+            service: api
+`
+
+	BeforeEach(func() {
+		registerTestCommand()
+		td = GinkgoT().TempDir()
+
+		load = func(commands string) (*Definition, error) {
+			_, d, err := loadTestDefinition(writeTestFile(td, "test-app.yaml", templates+commands))
+			return d, err
+		}
+	})
+
+	It("Should expand commands using templates", func() {
+		d, err := load(`commands:
+  - name: api
+    use: restart-service
+    with:
+      service: api
+  - name: web
+    description: Restarts the web server
+    use: restart-service
+    with:
+      service: nginx
+      signal: TERM
+    cheat:
+      cheat: restart the web server
+  - name: restart
+    description: Restarts services
+    use: group
+`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Commands).To(HaveLen(3))
+
+		api := d.Commands[0]
+		Expect(gjson.GetBytes(api, "name").String()).To(Equal("api"))
+		Expect(gjson.GetBytes(api, "description").String()).To(Equal("Restarts api"))
+		Expect(gjson.GetBytes(api, "banner").String()).To(Equal("kill -HUP $(cat /run/api.pid) $((1 + 1))\nif (( x > 1 )); then (( signal )); ((i++)); fi\n"))
+		Expect(gjson.GetBytes(api, "cheat.label").String()).To(Equal("api"))
+		Expect(gjson.GetBytes(api, "use").Exists()).To(BeFalse())
+		Expect(gjson.GetBytes(api, "with").Exists()).To(BeFalse())
+
+		web := d.Commands[1]
+		Expect(gjson.GetBytes(web, "description").String()).To(Equal("Restarts the web server"))
+		Expect(gjson.GetBytes(web, "banner").String()).To(ContainSubstring("kill -TERM $(cat /run/nginx.pid)"))
+		Expect(gjson.GetBytes(web, "cheat").Raw).To(MatchJSON(`{"label":"nginx","cheat":"restart the web server"}`))
+
+		restart := d.Commands[2]
+		Expect(gjson.GetBytes(restart, "commands.0.name").String()).To(Equal("api"))
+		Expect(gjson.GetBytes(restart, "commands.0.banner").String()).To(ContainSubstring("/run/api.pid"))
+
+		Expect(d.origins["$.commands[1]"].path).To(Equal("$.commands[1]"))
+	})
+
+	It("Should validate parameters", func() {
+		file := filepath.Join(td, "test-app.yaml")
+
+		_, err := load(`commands:
+  - name: api
+    use: restart-service
+    with:
+      signal: TERM
+      other: x
+`)
+		Expect(err).To(MatchError(fmt.Sprintf(`invalid definition: %s:30:5: template restart-service: parameter "service" is required, unknown parameter "other"`, file)))
+
+		_, err = load(`commands:
+  - name: api
+    use: unknown
+`)
+		Expect(err).To(MatchError(fmt.Sprintf(`invalid definition: %s:30:5: unknown template "unknown"`, file)))
+	})
+
+	It("Should detect undeclared parameters and cycles", func() {
+		_, err := load(`  undeclared:
+    command:
+      description: ((missing))
+      type: test
+  cycle:
+    command:
+      use: cycle
+commands:
+  - name: a
+    use: undeclared
+`)
+		Expect(err).To(MatchError(ContainSubstring(`template undeclared: undeclared parameter "missing"`)))
+
+		_, err = load(`  cycle:
+    command:
+      use: cycle
+commands:
+  - name: a
+    use: cycle
+`)
+		Expect(err).To(MatchError(ContainSubstring("template cycle cycle -> cycle")))
+	})
+})
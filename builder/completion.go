@@ -0,0 +1,113 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"text/template"
+
+	"github.com/choria-io/fisk"
+)
+
+// The completion scripts ask fisk for candidates using --completion-bash, when it has none the shell completes paths
+var (
+	completionScripts = map[string]string{
+		"bash": `# bash completion for {{ .Name }}, load using: source <({{ .Name }} completion bash)
+_{{ .Func }}_completion() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local out
+    out=$("${COMP_WORDS[0]}" --completion-bash "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null)
+    COMPREPLY=( $(compgen -W "${out}" -- "${cur}") )
+}
+complete -F _{{ .Func }}_completion -o default {{ .Name }}
+`,
+		"zsh": `#compdef {{ .Name }}
+# zsh completion for {{ .Name }}, load using: source <({{ .Name }} completion zsh)
+_{{ .Func }}_completion() {
+    local -a out
+    out=("${(@f)$(${words[1]} --completion-bash "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    out=(${out:#})
+
+    if (( ${#out} )); then
+        compadd -a out
+    else
+        _files
+    fi
+}
+compdef _{{ .Func }}_completion {{ .Name }}
+`,
+		"fish": `# fish completion for {{ .Name }}, load using: {{ .Name }} completion fish | source
+function __{{ .Func }}_completion
+    set -l cur (commandline -ct)
+    set -l tokens (commandline -opc) "$cur"
+    set -l out ($tokens[1] --completion-bash $tokens[2..-1] 2>/dev/null)
+
+    if test (count $out) -gt 0
+        printf '%s\n' $out
+    else
+        __fish_complete_path "$cur"
+    end
+end
+complete -c {{ .Name }} -f -a '(__{{ .Func }}_completion)'
+`,
+	}
+
+	completionFuncPattern = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// addCompletionCommand adds the hidden completion command that shows shell completion scripts
+func (b *AppBuilder) addCompletionCommand(app *fisk.Application) {
+	// applications can define their own completion command
+	if hasCommand(b.def.Commands, "completion") {
+		return
+	}
+
+	var shell string
+
+	cmd := app.Command("completion", "Shows the shell completion script").Hidden().Action(func(_ *fisk.ParseContext) error {
+		return b.writeCompletionScript(shell)
+	})
+	cmd.Arg("shell", "The shell to show the script for").Required().EnumVar(&shell, "bash", "zsh", "fish")
+}
+
+func (b *AppBuilder) writeCompletionScript(shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+
+	name := filepath.Base(b.name)
+
+	tmpl, err := template.New(shell).Parse(script)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(b.stdOut, map[string]string{
+		"Name": name,
+		"Func": completionFuncPattern.ReplaceAllString(name, "_"),
+	})
+}
+
+// hasCommand determines if cmds has a command called name or with name as alias
+func hasCommand(cmds []json.RawMessage, name string) bool {
+	for _, raw := range cmds {
+		var cmd GenericCommand
+		if json.Unmarshal(raw, &cmd) != nil {
+			continue
+		}
+
+		if cmd.Name == name || slices.Contains(cmd.Aliases, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Completion", func() {
+	var (
+		b   *AppBuilder
+		out *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		registerInheritingCommand()
+		out = &bytes.Buffer{}
+
+		var err error
+		b, err = New(context.Background(), "/usr/local/bin/my-app", WithLogger(NoopLogger{}), WithStdout(out), WithAppDefinitionBytes([]byte(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+flags:
+  - name: verbose
+    description: verbose
+    bool: true
+commands:
+  - name: deploy
+    description: deploy
+    type: inheriting
+    flags:
+      - name: env
+        description: env
+    commands:
+      - name: service
+        description: service
+        type: inheriting
+        aliases: [svc]
+        arguments:
+          - name: stage
+            description: stage
+            enum: [dev, prod]
+        flags:
+          - name: region
+            description: region
+            enum: [eu, us]
+          - name: namespace
+            description: namespace
+            complete: printf 'kube-system\ndefault\n\n'
+          - name: zone
+            description: zone
+            enum_from:
+              config: zones
+`)))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// complete shows the candidates fisk finds for args like the completion scripts do
+	complete := func(args ...string) []string {
+		app, err := b.createAppCLI()
+		Expect(err).ToNot(HaveOccurred())
+		app.Terminate(func(int) {})
+
+		r, w, err := os.Pipe()
+		Expect(err).ToNot(HaveOccurred())
+
+		stdout := os.Stdout
+		os.Stdout = w
+		app.Parse(append([]string{"--completion-bash"}, args...))
+		os.Stdout = stdout
+		w.Close()
+
+		out, err := io.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+
+		return strings.Fields(string(out))
+	}
+
+	It("Should complete commands and aliases", func() {
+		Expect(complete("")).To(Equal([]string{"help", "deploy"}))
+		Expect(complete("deploy", "s")).To(Equal([]string{"service"}))
+		Expect(complete("deploy", "svc", "--region", "")).To(Equal([]string{"eu", "us"}))
+	})
+
+	It("Should complete flags including inherited ones only below parents", func() {
+		Expect(complete("deploy", "--")).To(BeEmpty())
+		Expect(complete("deploy", "service", "--")).To(Equal([]string{"--region", "--namespace", "--zone", "--verbose", "--env"}))
+	})
+
+	It("Should complete values using hint actions", func() {
+		b.completionCache = GinkgoT().TempDir()
+		Expect(complete("deploy", "service", "--namespace", "")).To(Equal([]string{"kube-system", "default"}))
+
+		b.cfg["zones"] = map[string]any{"b": 1, "a": 2}
+		Expect(complete("deploy", "service", "--zone", "")).To(Equal([]string{"a", "b"}))
+	})
+
+	It("Should complete argument enums", func() {
+		Expect(complete("deploy", "service", "")).To(Equal([]string{"dev", "prod"}))
+		Expect(complete("deploy", "service", "--region", "eu", "p")).To(Equal([]string{"prod"}))
+		Expect(complete("deploy", "service", "prod", "")).To(BeEmpty())
+	})
+
+	Describe("completionHintAction", func() {
+		var td string
+
+		BeforeEach(func() {
+			td = GinkgoT().TempDir()
+			b.completionCache = filepath.Join(td, "cache")
+		})
+
+		It("Should cache values", func() {
+			counter := filepath.Join(td, "count")
+			source := &CompletionSource{Command: "echo x >> " + counter + "; echo one; echo two"}
+
+			Expect(b.completionHintAction(source)()).To(Equal([]string{"one", "two"}))
+			Expect(b.completionHintAction(source)()).To(Equal([]string{"one", "two"}))
+			Expect(os.ReadFile(counter)).To(Equal([]byte("x\n")))
+
+			source.Cache = "0s"
+			Expect(b.completionHintAction(source)()).To(Equal([]string{"one", "two"}))
+			Expect(os.ReadFile(counter)).To(Equal([]byte("x\nx\n")))
+		})
+
+		It("Should render templates", func() {
+			b.cfg["region"] = "eu"
+			Expect(b.completionHintAction(&CompletionSource{Command: "echo {{ .Config.region }}"})()).To(Equal([]string{"eu"}))
+		})
+
+		It("Should offer no values on failure or timeout", func() {
+			Expect(b.completionHintAction(&CompletionSource{Command: "echo x; exit 1"})()).To(BeEmpty())
+			Expect(b.completionHintAction(&CompletionSource{Command: "sleep 5; echo x", Timeout: "100ms"})()).To(BeEmpty())
+		})
+
+		It("Should validate settings", func() {
+			Expect((&CompletionSource{}).Validate()).To(MatchError("complete requires a command"))
+			Expect((&CompletionSource{Command: "x", Timeout: "soon"}).Validate()).To(MatchError(`invalid complete timeout "soon"`))
+			Expect((&CompletionSource{Command: "x", Cache: "-1s"}).Validate()).To(MatchError(`invalid complete cache "-1s"`))
+			Expect((&CompletionSource{Command: "x", Timeout: "1s", Cache: "0s"}).Validate()).To(Succeed())
+		})
+	})
+
+	It("Should show completion scripts", func() {
+		for _, shell := range []string{"bash", "zsh", "fish"} {
+			out.Reset()
+			Expect(b.writeCompletionScript(shell)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("--completion-bash"))
+			Expect(out.String()).To(ContainSubstring("completion for my-app"))
+			Expect(out.String()).To(ContainSubstring("_my_app_completion"))
+		}
+
+		Expect(b.writeCompletionScript("csh")).To(MatchError(`unsupported shell "csh"`))
+	})
+})
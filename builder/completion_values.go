@@ -0,0 +1,201 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+)
+
+var (
+	defaultCompletionTimeout = 2 * time.Second
+	defaultCompletionCache   = time.Minute
+)
+
+// CompletionSource runs a command to find the values offered when completing a flag or argument
+type CompletionSource struct {
+	// Command is a shell command, that can be a template, printing one value per line
+	Command string `json:"command"`
+	// Timeout is how long the command may run, defaults to 2s
+	Timeout string `json:"timeout,omitempty"`
+	// Cache is how long the values are reused for, defaults to 1m and 0s disables caching
+	Cache string `json:"cache,omitempty"`
+}
+
+// UnmarshalJSON accepts a command or a command with settings
+func (c *CompletionSource) UnmarshalJSON(data []byte) error {
+	var command string
+	if json.Unmarshal(data, &command) == nil {
+		*c = CompletionSource{Command: command}
+		return nil
+	}
+
+	type source CompletionSource
+	var s source
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return fmt.Errorf("complete must be a command or a command with settings")
+	}
+
+	*c = CompletionSource(s)
+
+	return nil
+}
+
+// JSONSchema describes CompletionSource in the application definition schema
+func (c CompletionSource) JSONSchema() map[string]any {
+	return map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{
+				"type":                 "object",
+				"required":             []string{"command"},
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"command": map[string]any{"type": "string"},
+					"timeout": map[string]any{"type": "string"},
+					"cache":   map[string]any{"type": "string"},
+				},
+			},
+		},
+	}
+}
+
+// Validate ensures the completion source is well-formed
+func (c *CompletionSource) Validate() error {
+	if c.Command == "" {
+		return fmt.Errorf("complete requires a command")
+	}
+
+	_, err := c.timeout()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.cache()
+
+	return err
+}
+
+func (c *CompletionSource) timeout() (time.Duration, error) {
+	if c.Timeout == "" {
+		return defaultCompletionTimeout, nil
+	}
+
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid complete timeout %q", c.Timeout)
+	}
+
+	return d, nil
+}
+
+func (c *CompletionSource) cache() (time.Duration, error) {
+	if c.Cache == "" {
+		return defaultCompletionCache, nil
+	}
+
+	d, err := time.ParseDuration(c.Cache)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid complete cache %q", c.Cache)
+	}
+
+	return d, nil
+}
+
+// completionHintAction offers the values found by source when completing using fisk, the command of source runs or
+// its values are read from the cache, failures result in no values
+func (b *AppBuilder) completionHintAction(source *CompletionSource) fisk.HintAction {
+	return func() []string {
+		command, err := b.RenderTemplate(source.Command, nil, nil)
+		if err != nil {
+			b.log.Debugf("Could not render completion command %q: %v", source.Command, err)
+			return nil
+		}
+
+		timeout, _ := source.timeout()
+		cache, _ := source.cache()
+
+		sum := sha256.Sum256([]byte(b.name + "\x00" + command))
+		cacheFile := filepath.Join(b.completionCache, hex.EncodeToString(sum[:]))
+
+		if cache > 0 {
+			stat, err := os.Stat(cacheFile)
+			if err == nil && time.Since(stat.ModTime()) < cache {
+				out, err := os.ReadFile(cacheFile)
+				if err == nil {
+					return splitValues(out)
+				}
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(b.ctx, timeout)
+		defer cancel()
+
+		out, err := b.runShellCommand(ctx, command)
+		if err != nil {
+			b.log.Debugf("Completion command %q failed: %v", command, err)
+			return nil
+		}
+
+		if cache > 0 && os.MkdirAll(b.completionCache, 0700) == nil {
+			err = os.WriteFile(cacheFile, out, 0600)
+			if err != nil {
+				b.log.Debugf("Could not cache completions: %v", err)
+			}
+		}
+
+		return splitValues(out)
+	}
+}
+
+// runShellCommand runs command using the shell set in SHELL or sh and returns its output
+func (b *AppBuilder) runShellCommand(ctx context.Context, command string) ([]byte, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	var stderr bytes.Buffer
+
+	run := exec.CommandContext(ctx, shell, "-c", command)
+	run.Stderr = &stderr
+
+	out, err := run.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// splitValues are the non empty lines in out
+func splitValues(out []byte) []string {
+	var values []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if v := strings.TrimSpace(scanner.Text()); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GenericConstraints are relationships between the flags of a command that are checked once the command is parsed
+type GenericConstraints struct {
+	// Exclusive are groups of flags where only one of the flags may be given
+	Exclusive [][]string `json:"exclusive,omitempty"`
+	// RequiredTogether are groups of flags that must all be given when any one of them is given
+	RequiredTogether [][]string `json:"required_together,omitempty"`
+	// AtLeastOne are groups of flags where at least one of the flags must be given
+	AtLeastOne [][]string `json:"at_least_one,omitempty"`
+}
+
+// Validate ensures the constraints only refer to optional flags of the command
+func (c *GenericConstraints) Validate(flags []GenericFlag) []string {
+	known := map[string]GenericFlag{}
+	for _, f := range flags {
+		known[f.Name] = f
+	}
+
+	var errs []string
+
+	check := func(kind string, groups [][]string) {
+		for _, group := range groups {
+			if len(group) < 2 {
+				errs = append(errs, fmt.Sprintf("%s constraint %v requires at least 2 flags", kind, group))
+			}
+
+			seen := map[string]bool{}
+			for _, name := range group {
+				f, ok := known[name]
+				switch {
+				case !ok:
+					errs = append(errs, fmt.Sprintf("%s constraint refers to unknown flag %q", kind, name))
+				case f.Required:
+					errs = append(errs, fmt.Sprintf("%s constraint can not include required flag %q", kind, name))
+				case seen[name]:
+					errs = append(errs, fmt.Sprintf("%s constraint lists flag %q more than once", kind, name))
+				}
+				seen[name] = true
+			}
+		}
+	}
+
+	check("exclusive", c.Exclusive)
+	check("required_together", c.RequiredTogether)
+	check("at_least_one", c.AtLeastOne)
+
+	return errs
+}
+
+// check ensures the flags that were given satisfy all constraints
+func (c *GenericConstraints) check(given func(name string) bool) error {
+	var errs []string
+
+	for _, group := range c.Exclusive {
+		var set []string
+		for _, name := range group {
+			if given(name) {
+				set = append(set, name)
+			}
+		}
+
+		if len(set) > 1 {
+			errs = append(errs, fmt.Sprintf("only one of %s can be given, got %s", flagList(group, "or"), flagList(set, "and")))
+		}
+	}
+
+	for _, group := range c.RequiredTogether {
+		var missing []string
+		for _, name := range group {
+			if !given(name) {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) > 0 && len(missing) < len(group) {
+			errs = append(errs, fmt.Sprintf("%s must be given together, missing %s", flagList(group, "and"), flagList(missing, "and")))
+		}
+	}
+
+	for _, group := range c.AtLeastOne {
+		var found bool
+		for _, name := range group {
+			if given(name) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			errs = append(errs, fmt.Sprintf("at least one of %s is required", flagList(group, "or")))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// help describes the constraints for the command help, empty when there are none
+func (c *GenericConstraints) help() string {
+	var lines []string
+
+	for _, group := range c.Exclusive {
+		lines = append(lines, fmt.Sprintf("  - Only one of %s can be given", flagList(group, "or")))
+	}
+	for _, group := range c.RequiredTogether {
+		lines = append(lines, fmt.Sprintf("  - %s must be given together", flagList(group, "and")))
+	}
+	for _, group := range c.AtLeastOne {
+		lines = append(lines, fmt.Sprintf("  - At least one of %s is required", flagList(group, "or")))
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return "Constraints:\n" + strings.Join(lines, "\n")
+}
+
+// flagGiven determines if the flag name was given on the command line, as recorded in setByUser, in its environment
+// variable or in its configuration key in cfg
+func flagGiven(cfg map[string]any, flags []GenericFlag, setByUser map[string]*bool, name string) bool {
+	if set, ok := setByUser[name]; ok && *set {
+		return true
+	}
+
+	for _, f := range flags {
+		if f.Name != name {
+			continue
+		}
+
+		if f.EnvVar != "" && os.Getenv(f.EnvVar) != "" {
+			return true
+		}
+
+		if f.Config != "" {
+			_, ok := configValue(cfg, f.Config)
+			return ok
+		}
+
+		return false
+	}
+
+	return false
+}
+
+// flagList formats names as flags like --a, --b or --c
+func flagList(names []string, conjunction string) string {
+	flags := make([]string, len(names))
+	for i, n := range names {
+		flags[i] = "--" + n
+	}
+
+	if len(flags) == 1 {
+		return flags[0]
+	}
+
+	return fmt.Sprintf("%s %s %s", strings.Join(flags[:len(flags)-1], ", "), conjunction, flags[len(flags)-1])
+}
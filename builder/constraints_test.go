@@ -0,0 +1,135 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/choria-io/fisk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Constraints", func() {
+	var (
+		d   *GenericCommand
+		cfg map[string]any
+		ran bool
+		cb  = func(_ *fisk.ParseContext) error { ran = true; return nil }
+	)
+
+	BeforeEach(func() {
+		ran = false
+		cfg = map[string]any{}
+		d = &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{
+			{Name: "file", Description: "help"},
+			{Name: "url", Description: "help", EnvVar: "GINKGO_URL"},
+			{Name: "user", Description: "help"},
+			{Name: "password", Description: "help"},
+		}
+		d.Constraints = &GenericConstraints{
+			Exclusive:        [][]string{{"file", "url"}},
+			AtLeastOne:       [][]string{{"file", "url"}},
+			RequiredTogether: [][]string{{"user", "password"}},
+		}
+	})
+
+	parse := func(args ...string) error {
+		b := &AppBuilder{ctx: context.Background(), log: NoopLogger{}, cfg: cfg, stdOut: &bytes.Buffer{}}
+		app := fisk.New("app", "app")
+		app.Terminate(func(int) {})
+		CreateGenericCommand(app, d, map[string]any{}, map[string]any{}, b, cb)
+
+		_, err := app.Parse(append([]string{"ginkgo"}, args...))
+
+		return err
+	}
+
+	Describe("Validate", func() {
+		It("Should accept valid constraints", func() {
+			Expect(d.Constraints.Validate(d.Flags)).To(BeEmpty())
+		})
+
+		It("Should detect invalid groups", func() {
+			d.Flags[0].Required = true
+			d.Constraints.Exclusive = [][]string{{"file", "other"}, {"url"}}
+			d.Constraints.RequiredTogether = [][]string{{"user", "user"}}
+
+			Expect(d.Constraints.Validate(d.Flags)).To(Equal([]string{
+				`exclusive constraint can not include required flag "file"`,
+				`exclusive constraint refers to unknown flag "other"`,
+				`exclusive constraint [url] requires at least 2 flags`,
+				`required_together constraint lists flag "user" more than once`,
+				`at_least_one constraint can not include required flag "file"`,
+			}))
+		})
+	})
+
+	Describe("check", func() {
+		It("Should accept satisfied constraints", func() {
+			Expect(parse("--file", "x")).To(Succeed())
+			Expect(ran).To(BeTrue())
+
+			Expect(parse("--url", "x", "--user", "u", "--password", "p")).To(Succeed())
+		})
+
+		It("Should treat environment variables as given", func() {
+			GinkgoT().Setenv("GINKGO_URL", "http://example.net")
+
+			Expect(parse()).To(Succeed())
+			Expect(parse("--file", "x")).To(MatchError("only one of --file or --url can be given, got --file and --url"))
+		})
+
+		It("Should treat configuration values as given", func() {
+			d.Flags[1].Config = "service.url"
+			d.Flags[3].Default = "secret"
+
+			cfg["service"] = map[string]any{"url": "http://example.net"}
+			Expect(parse()).To(Succeed())
+			Expect(parse("--file", "x")).To(MatchError("only one of --file or --url can be given, got --file and --url"))
+
+			// defaults are not considered given
+			Expect(parse("--user", "u")).To(MatchError("--user and --password must be given together, missing --password"))
+
+			delete(cfg, "service")
+			Expect(parse()).To(MatchError("at least one of --file or --url is required"))
+		})
+
+		It("Should detect violations", func() {
+			Expect(parse("--file", "x", "--url", "y")).To(MatchError("only one of --file or --url can be given, got --file and --url"))
+			Expect(parse()).To(MatchError("at least one of --file or --url is required"))
+			Expect(parse("--file", "x", "--user", "u")).To(MatchError("--user and --password must be given together, missing --password"))
+			Expect(ran).To(BeFalse())
+		})
+
+		It("Should report all violations", func() {
+			err := parse("--password", "p")
+			Expect(err).To(MatchError("--user and --password must be given together, missing --user, at least one of --file or --url is required"))
+		})
+	})
+
+	Describe("help", func() {
+		It("Should describe the constraints", func() {
+			Expect(d.Constraints.help()).To(Equal(`Constraints:
+  - Only one of --file or --url can be given
+  - --user and --password must be given together
+  - At least one of --file or --url is required`))
+			Expect((&GenericConstraints{}).help()).To(BeEmpty())
+		})
+
+		It("Should show constraints in the command help", func() {
+			app := fisk.New("app", "app")
+			cmd := CreateGenericCommand(app, d, map[string]any{}, map[string]any{}, nil, cb)
+			Expect(cmd.Model().Help).To(ContainSubstring("help\n\nConstraints:\n  - Only one of --file or --url can be given"))
+		})
+
+		It("Should format flag lists", func() {
+			Expect(flagList([]string{"a"}, "or")).To(Equal("--a"))
+			Expect(flagList([]string{"a", "b", "c"}, "and")).To(Equal("--a, --b and --c"))
+		})
+	})
+})
@@ -24,12 +24,28 @@ type Definition struct {
 	Version      string    `json:"version"`
 	Author       string    `json:"author"`
 	Cheats       *AppCheat `json:"cheat"`
-	HelpTemplate string    `json:"help_template"`
-	IncludeFile  string    `json:"include_file"`
+	HelpTemplate string    `json:"help_template,omitempty"`
+	IncludeFile  Includes  `json:"include_file"`
+	// AllowUnknownKeys disables the rejection of unknown keys, useful when definitions target newer versions
+	AllowUnknownKeys bool `json:"allow_unknown_keys,omitempty"`
+	// Lint configures appbuilder lint
+	Lint *LintSettings `json:"lint"`
+	// Templates are commands that can be used by many commands with different parameters
+	Templates map[string]*CommandTemplate `json:"templates"`
+	// PromptMissing asks for the values of all required arguments and flags interactively when not given
+	PromptMissing bool `json:"prompt_missing,omitempty"`
+	// Flags are added to every command in the application
+	Flags []GenericFlag `json:"flags,omitempty"`
 
 	GenericSubCommands
 
 	commands []Command
+	// origins are where commands are defined keyed by their path in the definition after expanding includes
+	origins map[string]commandOrigin
+	// unknownKeys are keys in the definition that are not known properties
+	unknownKeys []unknownKey
+	// overlays are the overlay files applied to the definition in the order they were applied
+	overlays []string
 }
 
 const (
@@ -56,6 +72,8 @@ func (d *Definition) Validate(log Logger) error {
 		errs = append(errs, "no commands defined")
 	}
 
+	errs = append(errs, validateFlags(d.Flags)...)
+
 	ht := strings.TrimSpace(strings.ToLower(d.HelpTemplate))
 	if !(ht == "" || ht == "compact" || ht == "long" || ht == "default" || ht == "short") {
 		errs = append(errs, "help_template must be one of long, short, compact, default or unset")
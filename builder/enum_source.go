@@ -0,0 +1,215 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+)
+
+var defaultEnumTimeout = 5 * time.Second
+
+// EnumSource finds the valid values of a flag or argument at run time
+type EnumSource struct {
+	// Config is the dotted path to a list or map in the configuration, for maps the keys are valid values
+	Config string `json:"config,omitempty"`
+	// Command is a shell command, that can be a template, printing one value per line
+	Command string `json:"command,omitempty"`
+	// Timeout is how long the command may run, defaults to 5s
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Validate ensures the enum source is well-formed
+func (e *EnumSource) Validate() error {
+	switch {
+	case e.Config == "" && e.Command == "":
+		return fmt.Errorf("enum_from requires config or command")
+	case e.Config != "" && e.Command != "":
+		return fmt.Errorf("enum_from can only set one of config or command")
+	case e.Config != "" && e.Timeout != "":
+		return fmt.Errorf("enum_from timeout requires a command")
+	}
+
+	_, err := e.timeout()
+
+	return err
+}
+
+func (e *EnumSource) timeout() (time.Duration, error) {
+	if e.Timeout == "" {
+		return defaultEnumTimeout, nil
+	}
+
+	d, err := time.ParseDuration(e.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid enum_from timeout %q", e.Timeout)
+	}
+
+	return d, nil
+}
+
+// enumFromValue is a fisk value accepting only the values found by options, options are only found once a value is set.
+// When values is set all values are kept rather than value being set.
+type enumFromValue struct {
+	value   *string
+	values  *[]string
+	options func() ([]string, error)
+}
+
+func (v *enumFromValue) String() string {
+	if v.values != nil {
+		return strings.Join(*v.values, ",")
+	}
+
+	return *v.value
+}
+
+// IsCumulative tells fisk the value can be set multiple times
+func (v *enumFromValue) IsCumulative() bool {
+	return v.values != nil
+}
+
+func (v *enumFromValue) Set(value string) error {
+	options, err := v.options()
+	if err != nil {
+		return fmt.Errorf("could not determine valid values: %w", err)
+	}
+
+	for _, o := range options {
+		if o != value {
+			continue
+		}
+
+		if v.values != nil {
+			*v.values = append(*v.values, value)
+		} else {
+			*v.value = value
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("enum value must be one of %s, got '%s'", strings.Join(options, ","), value)
+}
+
+// applyEnumFrom configures c to only accept the values found by source and returns the fisk value pointer. Sources are
+// resolved when a value is set rather than when commands are created so commands only run for the command being invoked.
+func (b *AppBuilder) applyEnumFrom(c parserClause, source *EnumSource, multiple bool) any {
+	value := &enumFromValue{
+		options: sync.OnceValues(func() ([]string, error) {
+			return b.enumValues(source)
+		}),
+	}
+
+	c.SetValue(value)
+
+	if multiple {
+		value.values = &[]string{}
+		return value.values
+	}
+
+	value.value = new(string)
+
+	return value.value
+}
+
+// enumHintAction offers the values found by source when completing using fisk
+func (b *AppBuilder) enumHintAction(source *EnumSource) fisk.HintAction {
+	return func() []string {
+		values, err := b.enumValues(source)
+		if err != nil {
+			b.log.Debugf("Could not determine valid values: %v", err)
+		}
+
+		return values
+	}
+}
+
+// enumValues finds the values of source in the configuration or by running its command
+func (b *AppBuilder) enumValues(source *EnumSource) ([]string, error) {
+	if source.Config != "" {
+		return configEnumValues(b.cfg, source.Config)
+	}
+
+	command, err := b.RenderTemplate(source.Command, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := source.timeout()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, timeout)
+	defer cancel()
+
+	out, err := b.runShellCommand(ctx, command)
+	if err != nil {
+		return nil, fmt.Errorf("command %q failed: %w", command, err)
+	}
+
+	values := splitValues(out)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("command %q produced no values", command)
+	}
+
+	return values, nil
+}
+
+// configEnumValues finds the values of the list or map at the dotted path key in cfg
+func configEnumValues(cfg map[string]any, key string) ([]string, error) {
+	val, ok := configValue(cfg, key)
+	if !ok {
+		return nil, fmt.Errorf("configuration key %q not found", key)
+	}
+
+	var values []string
+
+	switch v := val.(type) {
+	case []any:
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+	case map[string]any:
+		for k := range v {
+			values = append(values, k)
+		}
+		sort.Strings(values)
+	default:
+		return nil, fmt.Errorf("configuration key %q is not a list or map", key)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("configuration key %q has no values", key)
+	}
+
+	return values, nil
+}
+
+// configValue finds the value at the dotted path key in cfg
+func configValue(cfg map[string]any, key string) (any, bool) {
+	var cur any = cfg
+
+	for _, part := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
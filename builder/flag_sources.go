@@ -0,0 +1,166 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/choria-io/fisk"
+)
+
+// flagInput is a flag whose value is found, in order of precedence, on the command line, in the environment, in the
+// configuration or from its default
+type flagInput struct {
+	flag      GenericFlag
+	dType     string
+	value     fisk.Value
+	target    any
+	setByUser *bool
+	prompt    bool
+	// check is the validation applied by fisk to values given on the command line
+	check fisk.OptionValidator
+}
+
+// resolveFlags sets the configuration values of flags not given on the command line or in the environment, replacing
+// any default set by fisk, and logs where the value of every flag came from.
+func (b *AppBuilder) resolveFlags(inputs []*flagInput) error {
+	for _, input := range inputs {
+		source, err := input.resolve(b.cfg)
+		if err != nil {
+			return err
+		}
+
+		if source == "" {
+			b.log.Debugf("Flag %q has no value", input.flag.Name)
+			continue
+		}
+
+		b.log.Debugf("Flag %q set from %s", input.flag.Name, source)
+	}
+
+	return nil
+}
+
+// resolve sets the value of the flag when needed and describes its source, empty when it has no value
+func (i *flagInput) resolve(cfg map[string]any) (string, error) {
+	f := i.flag
+
+	switch {
+	case *i.setByUser:
+		return "the command line", nil
+
+	case f.EnvVar != "" && os.Getenv(f.EnvVar) != "":
+		return fmt.Sprintf("environment variable %s", f.EnvVar), nil
+
+	case f.Config == "":
+		if f.Default != nil {
+			return "its default", nil
+		}
+
+		return "", nil
+	}
+
+	val, ok := configValue(cfg, f.Config)
+	if ok {
+		values, err := configFlagValues(val, f.Multiple, i.dType == "map")
+		if err == nil {
+			i.reset()
+			err = i.set(values)
+		}
+		if err != nil {
+			return "", fmt.Errorf("flag %q: invalid value in configuration key %q: %w", f.Name, f.Config, err)
+		}
+
+		return fmt.Sprintf("configuration key %s", f.Config), nil
+	}
+
+	switch {
+	case f.Default != nil:
+		return "its default", nil
+
+	case f.Required && !i.prompt:
+		return "", fmt.Errorf("%w --%s not provided", fisk.ErrRequiredFlag, f.Name)
+	}
+
+	return "", nil
+}
+
+// reset removes the default set by fisk so values accepting multiple values do not add to it
+func (i *flagInput) reset() {
+	if r, ok := i.value.(interface{ reset() }); ok {
+		r.reset()
+		return
+	}
+
+	v := reflect.ValueOf(i.target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return
+	}
+
+	switch e := v.Elem(); e.Kind() {
+	case reflect.Map:
+		e.Set(reflect.MakeMap(e.Type()))
+	case reflect.Slice:
+		e.SetZero()
+	}
+}
+
+func (i *flagInput) set(values []string) error {
+	for _, v := range values {
+		if i.check != nil {
+			err := i.check(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := i.value.Set(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configFlagValues are the flag values of a configuration value, lists are only accepted by flags accepting multiple
+// values and maps only by map flags
+func configFlagValues(val any, multiple bool, isMap bool) ([]string, error) {
+	switch v := val.(type) {
+	case []any:
+		if !multiple {
+			return nil, fmt.Errorf("a list can only be used by flags accepting multiple values")
+		}
+
+		var res []string
+		for _, item := range v {
+			res = append(res, defaultHint(item))
+		}
+
+		return res, nil
+
+	case map[string]any:
+		if !isMap {
+			return nil, fmt.Errorf("a map can only be used by flags of type map")
+		}
+
+		var res []string
+		for k, item := range v {
+			res = append(res, fmt.Sprintf("%s=%s", k, defaultHint(item)))
+		}
+		sort.Strings(res)
+
+		return res, nil
+
+	case nil:
+		return nil, fmt.Errorf("no value set")
+
+	default:
+		return []string{defaultHint(v)}, nil
+	}
+}
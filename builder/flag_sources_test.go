@@ -0,0 +1,177 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/choria-io/fisk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// recordingLogger keeps debug messages for inspection
+type recordingLogger struct {
+	NoopLogger
+	debug []string
+}
+
+func (l *recordingLogger) Debugf(format string, v ...any) {
+	l.debug = append(l.debug, fmt.Sprintf(format, v...))
+}
+
+var _ = Describe("Flag sources", func() {
+	var (
+		b   *AppBuilder
+		log *recordingLogger
+		cb  = func(_ *fisk.ParseContext) error { return nil }
+	)
+
+	BeforeEach(func() {
+		log = &recordingLogger{}
+		b = &AppBuilder{ctx: context.Background(), log: log, stdOut: &bytes.Buffer{}, def: &Definition{}, cfg: map[string]any{
+			"deploy": map[string]any{
+				"cluster": "staging",
+				"zones":   []any{"a", "b"},
+				"labels":  map[string]any{"team": "ops", "tier": 1},
+				"retries": 3,
+			},
+		}}
+	})
+
+	parse := func(d *GenericCommand, args ...string) (map[string]any, error) {
+		flags := map[string]any{}
+		app := fisk.New("app", "app")
+		app.Terminate(func(int) {})
+		CreateGenericCommand(app, d, map[string]any{}, flags, b, cb)
+
+		_, err := app.Parse(append([]string{"ginkgo"}, args...))
+
+		return dereferenceArgsOrFlags(flags), err
+	}
+
+	It("Should prefer the command line, then the environment, then the configuration and finally the default", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{{Name: "cluster", Description: "help", EnvVar: "GINKGO_CLUSTER", Config: "deploy.cluster", Default: "dev"}}
+
+		flags, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["cluster"]).To(Equal("staging"))
+		Expect(log.debug).To(ContainElement(`Flag "cluster" set from configuration key deploy.cluster`))
+
+		GinkgoT().Setenv("GINKGO_CLUSTER", "qa")
+		flags, err = parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["cluster"]).To(Equal("qa"))
+		Expect(log.debug).To(ContainElement(`Flag "cluster" set from environment variable GINKGO_CLUSTER`))
+
+		flags, err = parse(d, "--cluster", "prod")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["cluster"]).To(Equal("prod"))
+		Expect(log.debug).To(ContainElement(`Flag "cluster" set from the command line`))
+
+		GinkgoT().Setenv("GINKGO_CLUSTER", "")
+		b.cfg = map[string]any{}
+		flags, err = parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["cluster"]).To(Equal("dev"))
+		Expect(log.debug).To(ContainElement(`Flag "cluster" set from its default`))
+	})
+
+	It("Should set typed, list and map values from the configuration", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{
+			{Name: "retries", Description: "help", Type: "int", Config: "deploy.retries"},
+			{Name: "zones", Description: "help", Multiple: true, Config: "deploy.zones", Default: []any{"x"}},
+			{Name: "labels", Description: "help", Type: "map", Config: "deploy.labels"},
+			{Name: "other", Description: "help"},
+		}
+
+		flags, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["retries"]).To(Equal(int64(3)))
+		Expect(flags["zones"]).To(Equal([]string{"a", "b"}))
+		Expect(flags["labels"]).To(Equal(map[string]string{"team": "ops", "tier": "1"}))
+		Expect(log.debug).To(ContainElement(`Flag "other" has no value`))
+	})
+
+	It("Should replace defaults of map and parsed flags with configuration values", func() {
+		b.cfg["deploy"].(map[string]any)["dates"] = []any{"2026-01-02"}
+
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{
+			{Name: "labels", Description: "help", Type: "map", Config: "deploy.labels", Default: "env=dev"},
+			{Name: "dates", Description: "help", Type: "date", Multiple: true, Config: "deploy.dates", Default: []any{"2025-01-01"}},
+		}
+
+		flags, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["labels"]).To(Equal(map[string]string{"team": "ops", "tier": "1"}))
+		Expect(flags["dates"]).To(HaveLen(1))
+	})
+
+	It("Should show the defaults of flags with a configuration key in help", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{{Name: "cluster", Description: "The cluster", Config: "deploy.cluster", Default: "dev"}}
+
+		usage := &bytes.Buffer{}
+		app := fisk.New("app", "app")
+		app.Terminate(func(int) {})
+		app.UsageWriter(usage)
+		CreateGenericCommand(app, d, map[string]any{}, map[string]any{}, b, cb)
+
+		_, err := app.Parse([]string{"ginkgo", "--help"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage.String()).To(MatchRegexp(`--cluster="dev"\s+The cluster`))
+	})
+
+	It("Should fail for invalid configuration values", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{{Name: "zones", Description: "help", Config: "deploy.zones"}}
+		_, err := parse(d)
+		Expect(err).To(MatchError(`flag "zones": invalid value in configuration key "deploy.zones": a list can only be used by flags accepting multiple values`))
+
+		d.Flags = []GenericFlag{{Name: "labels", Description: "help", Config: "deploy.labels"}}
+		_, err = parse(d)
+		Expect(err).To(MatchError(`flag "labels": invalid value in configuration key "deploy.labels": a map can only be used by flags of type map`))
+
+		d.Flags = []GenericFlag{{Name: "cluster", Description: "help", Enum: []string{"dev", "prod"}, Config: "deploy.cluster"}}
+		_, err = parse(d)
+		Expect(err).To(MatchError(ContainSubstring(`flag "cluster": invalid value in configuration key "deploy.cluster": enum value must be one of dev,prod`)))
+
+		d.Flags = []GenericFlag{{Name: "cluster", Description: "help", ValidationExpression: `value == "prod"`, Config: "deploy.cluster"}}
+		_, err = parse(d)
+		Expect(err).To(MatchError(ContainSubstring(`flag "cluster": invalid value in configuration key "deploy.cluster": validation using`)))
+	})
+
+	It("Should only require flags missing from the configuration", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{{Name: "cluster", Description: "help", Required: true, Config: "deploy.cluster"}}
+
+		flags, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["cluster"]).To(Equal("staging"))
+
+		b.cfg = map[string]any{}
+		_, err = parse(d)
+		Expect(err).To(MatchError(fisk.ErrRequiredFlag))
+		Expect(err).To(MatchError("required flag --cluster not provided"))
+	})
+
+	It("Should not prompt for values found in the configuration", func() {
+		origTerminal := stdinIsTerminal
+		DeferCleanup(func() { stdinIsTerminal = origTerminal })
+		stdinIsTerminal = func() bool { return true }
+
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Flags = []GenericFlag{{Name: "cluster", Description: "help", Required: true, Prompt: true, Config: "deploy.cluster"}}
+
+		flags, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(flags["cluster"]).To(Equal("staging"))
+	})
+})
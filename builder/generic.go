@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -46,6 +47,8 @@ type GenericCommand struct {
 	Cheat         *GenericCommandCheat `json:"cheat,omitempty"`
 	Tags          []string             `json:"tags,omitempty"`
 	Secrets       []GenericSecret      `json:"secrets,omitempty"`
+	// Constraints are relationships between flags checked once the command is parsed
+	Constraints *GenericConstraints `json:"constraints,omitempty"`
 }
 
 // Validate ensures the command is well-formed
@@ -66,17 +69,25 @@ func (c *GenericCommand) Validate(logger Logger) error {
 		errs = append(errs, "cheats require a body")
 	}
 
-	for _, a := range c.Arguments {
-		errs = append(errs, validateInput("argument", a.Name, a.Type, a.Default, len(a.Enum) > 0, false)...)
-	}
-
-	for _, f := range c.Flags {
-		if len(f.Short) > 1 {
-			errs = append(errs, fmt.Sprintf("short flag for %s must be 1 character", f.Name))
+	for i, a := range c.Arguments {
+		errs = append(errs, validateInput("argument", a.Name, a.Type, a.Default, len(a.Enum) > 0 || a.EnumFrom != nil, false, a.Multiple)...)
+		if normalizeType(a.Type) == "map" {
+			errs = append(errs, fmt.Sprintf("argument %q can not be of type map, only flags support maps", a.Name))
+		}
+		if a.Multiple && i != len(c.Arguments)-1 {
+			errs = append(errs, fmt.Sprintf("argument %q accepts multiple values so must be the last argument", a.Name))
+		}
+		errs = append(errs, validateInputSources("argument", a.Name, a.Enum, a.EnumFrom, a.Complete)...)
+		if a.Layout != "" && normalizeType(a.Type) != "date" {
+			errs = append(errs, fmt.Sprintf("argument %q sets a layout but is not of type date", a.Name))
+		}
+		if a.Prompt && (!a.Required || a.Default != nil) {
+			errs = append(errs, fmt.Sprintf("argument %q can only prompt when required and without a default", a.Name))
 		}
-		errs = append(errs, validateInput("flag", f.Name, f.Type, f.Default, len(f.Enum) > 0, f.Bool)...)
 	}
 
+	errs = append(errs, validateFlags(c.Flags)...)
+
 	seenSecrets := map[string]struct{}{}
 	for _, s := range c.Secrets {
 		err := s.Validate()
@@ -98,6 +109,27 @@ func (c *GenericCommand) Validate(logger Logger) error {
 	return nil
 }
 
+// validateFlags checks the settings of flags
+func validateFlags(flags []GenericFlag) []string {
+	var errs []string
+
+	for _, f := range flags {
+		if len(f.Short) > 1 {
+			errs = append(errs, fmt.Sprintf("short flag for %s must be 1 character", f.Name))
+		}
+		errs = append(errs, validateInput("flag", f.Name, f.Type, f.Default, len(f.Enum) > 0 || f.EnumFrom != nil, f.Bool, f.Multiple)...)
+		errs = append(errs, validateInputSources("flag", f.Name, f.Enum, f.EnumFrom, f.Complete)...)
+		if f.Layout != "" && normalizeType(f.Type) != "date" {
+			errs = append(errs, fmt.Sprintf("flag %q sets a layout but is not of type date", f.Name))
+		}
+		if f.Prompt && (!f.Required || f.Default != nil) {
+			errs = append(errs, fmt.Sprintf("flag %q can only prompt when required and without a default", f.Name))
+		}
+	}
+
+	return errs
+}
+
 // GenericArgument is a standard command line argument
 type GenericArgument struct {
 	Name                 string   `json:"name"`
@@ -107,6 +139,14 @@ type GenericArgument struct {
 	Default              any      `json:"default"`
 	ValidationExpression string   `json:"validate"`
 	Type                 string   `json:"type"`
+	Multiple             bool     `json:"multiple"`
+	Layout               string   `json:"layout,omitempty"`
+	// Prompt asks for the value interactively when required and not given
+	Prompt bool `json:"prompt,omitempty"`
+	// EnumFrom finds the valid values at run time instead of Enum
+	EnumFrom *EnumSource `json:"enum_from,omitempty"`
+	// Complete finds the values offered when completing the argument
+	Complete *CompletionSource `json:"complete,omitempty"`
 }
 
 // GenericFlag is a standard command line flag
@@ -122,6 +162,16 @@ type GenericFlag struct {
 	Short                string   `json:"short"`
 	ValidationExpression string   `json:"validate"`
 	Type                 string   `json:"type"`
+	Multiple             bool     `json:"multiple"`
+	Layout               string   `json:"layout,omitempty"`
+	// Config is the dotted path to a configuration value used when the flag is not given or set in the environment
+	Config string `json:"config,omitempty"`
+	// Prompt asks for the value interactively when required and not given
+	Prompt bool `json:"prompt,omitempty"`
+	// EnumFrom finds the valid values at run time instead of Enum
+	EnumFrom *EnumSource `json:"enum_from,omitempty"`
+	// Complete finds the values offered when completing the flag
+	Complete *CompletionSource `json:"complete,omitempty"`
 }
 
 // parserClause is the shared surface of fisk's *ArgClause and *FlagClause that we use to
@@ -131,6 +181,7 @@ type parserClause interface {
 	Bool() *bool
 	UnNegatableBool() *bool
 	Enum(...string) *string
+	Enums(...string) *[]string
 	ExistingFile() *string
 	ExistingDir() *string
 	Counter() *int
@@ -147,6 +198,23 @@ type parserClause interface {
 	Float() *float64
 	Float32() *float32
 	Float64() *float64
+	Strings() *[]string
+	ExistingFiles() *[]string
+	ExistingDirs() *[]string
+	Ints() *[]int
+	Int8List() *[]int8
+	Int16List() *[]int16
+	Int32List() *[]int32
+	Int64List() *[]int64
+	Uints() *[]uint
+	Uint8List() *[]uint8
+	Uint16List() *[]uint16
+	Uint32List() *[]uint32
+	Uint64List() *[]uint64
+	Float32List() *[]float32
+	Float64List() *[]float64
+	StringMap() *map[string]string
+	SetValue(fisk.Value)
 }
 
 var (
@@ -176,6 +244,29 @@ var inputTypes = map[string]func(parserClause) any{
 	"float":         func(c parserClause) any { return c.Float() },
 	"float32":       func(c parserClause) any { return c.Float32() },
 	"float64":       func(c parserClause) any { return c.Float64() },
+	"map":           func(c parserClause) any { return c.StringMap() },
+}
+
+// multipleInputTypes are the fisk parsers used for types that accept multiple values, types
+// missing here like counter can not be used with multiple.
+var multipleInputTypes = map[string]func(parserClause) any{
+	"string":        func(c parserClause) any { return c.Strings() },
+	"existing_file": func(c parserClause) any { return c.ExistingFiles() },
+	"existing_dir":  func(c parserClause) any { return c.ExistingDirs() },
+	"int":           func(c parserClause) any { return c.Ints() },
+	"integer":       func(c parserClause) any { return c.Ints() },
+	"int8":          func(c parserClause) any { return c.Int8List() },
+	"int16":         func(c parserClause) any { return c.Int16List() },
+	"int32":         func(c parserClause) any { return c.Int32List() },
+	"int64":         func(c parserClause) any { return c.Int64List() },
+	"uint":          func(c parserClause) any { return c.Uints() },
+	"uint8":         func(c parserClause) any { return c.Uint8List() },
+	"uint16":        func(c parserClause) any { return c.Uint16List() },
+	"uint32":        func(c parserClause) any { return c.Uint32List() },
+	"uint64":        func(c parserClause) any { return c.Uint64List() },
+	"float":         func(c parserClause) any { return c.Float64List() },
+	"float32":       func(c parserClause) any { return c.Float32List() },
+	"float64":       func(c parserClause) any { return c.Float64List() },
 }
 
 // normalizeType lower-cases and trims a user supplied type so matching is forgiving and
@@ -187,16 +278,19 @@ func normalizeType(t string) string {
 // knownInputType reports whether dType, already normalized, is one of the mapped types.
 func knownInputType(dType string) bool {
 	_, ok := inputTypes[dType]
-	return ok
+	return ok || isParsedInputType(dType)
 }
 
 // knownTypeNames returns all supported type names including bool, sorted for stable help
 // and error output.
 func knownTypeNames() []string {
-	names := make([]string, 0, len(inputTypes)+1)
+	names := make([]string, 0, len(inputTypes)+len(parsedInputTypes)+1)
 	for name := range inputTypes {
 		names = append(names, name)
 	}
+	for name := range parsedInputTypes {
+		names = append(names, name)
+	}
 	names = append(names, "bool")
 	sort.Strings(names)
 
@@ -219,10 +313,18 @@ func isTrueDefault(dflt any) bool {
 
 // applyInputType configures c for the requested type and returns the fisk value pointer.
 // enum takes precedence over type, then bool, then the mapped types, finally a plain string.
-func applyInputType(c parserClause, dType string, enum []string, dflt any) any {
+// When multiple is set the value is a pointer to a slice of the type.
+func applyInputType(c parserClause, dType string, enum []string, dflt any, multiple bool) any {
 	switch {
+	case len(enum) > 0 && multiple:
+		return c.Enums(enum...)
 	case len(enum) > 0:
 		return c.Enum(enum...)
+	case multiple:
+		if fn, ok := multipleInputTypes[dType]; ok {
+			return fn(c)
+		}
+		return c.Strings()
 	case dType == "bool":
 		if isTrueDefault(dflt) {
 			return c.Bool()
@@ -247,20 +349,56 @@ func defaultHint(dflt any) string {
 	return fmt.Sprintf("%v", dflt)
 }
 
+// defaultValues are the values of a default, lists are used when accepting multiple values.
+func defaultValues(dflt any) []string {
+	list, ok := dflt.([]any)
+	if !ok {
+		return []string{fmt.Sprintf("%v", dflt)}
+	}
+
+	var res []string
+	for _, v := range list {
+		res = append(res, fmt.Sprintf("%v", v))
+	}
+
+	return res
+}
+
 // validateInput checks the type and default of a flag or argument. kind is "flag" or
 // "argument" and is used in messages, legacyBool is the deprecated bool flag field.
-func validateInput(kind, name, typ string, dflt any, hasEnum, legacyBool bool) []string {
+func validateInput(kind, name, typ string, dflt any, hasEnum, legacyBool, multiple bool) []string {
 	var errs []string
 
 	// fisk only takes string defaults, so numbers and the like must be quoted to reach it
 	// unambiguously. Booleans are allowed as a convenience.
-	switch dflt.(type) {
+	switch v := dflt.(type) {
 	case nil, string, bool:
+	case []any:
+		if !multiple && normalizeType(typ) != "map" {
+			errs = append(errs, fmt.Sprintf("%s %q default can only be a list when accepting multiple values or of type map", kind, name))
+		}
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				errs = append(errs, fmt.Sprintf("%s %q default values must be strings, quote the value like %q", kind, name, defaultHint(item)))
+			}
+		}
 	default:
 		errs = append(errs, fmt.Sprintf("%s %q default must be a string or boolean, quote the value like default: %q", kind, name, defaultHint(dflt)))
 	}
 
 	dType := normalizeType(typ)
+
+	if multiple {
+		mType := dType
+		if legacyBool {
+			mType = "bool"
+		}
+
+		if _, ok := multipleInputTypes[mType]; !ok && !isParsedInputType(mType) && (mType == "bool" || knownInputType(mType)) {
+			errs = append(errs, fmt.Sprintf("%s %q can not accept multiple values of type %q", kind, name, mType))
+		}
+	}
+
 	if dType == "" {
 		return errs
 	}
@@ -279,16 +417,87 @@ func validateInput(kind, name, typ string, dflt any, hasEnum, legacyBool bool) [
 	return errs
 }
 
+// validateInputSources checks the settings that find valid and suggested values of a flag or argument
+func validateInputSources(kind, name string, enum []string, enumFrom *EnumSource, complete *CompletionSource) []string {
+	var errs []string
+
+	if enumFrom != nil {
+		if len(enum) > 0 {
+			errs = append(errs, fmt.Sprintf("%s %q sets both enum and enum_from, remove one", kind, name))
+		}
+
+		err := enumFrom.Validate()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %v", kind, name, err))
+		}
+	}
+
+	if complete != nil {
+		err := complete.Validate()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %v", kind, name, err))
+		}
+	}
+
+	return errs
+}
+
+// mapEntrySeparator splits map entries into key and value the same way fisk does
+var mapEntrySeparator = regexp.MustCompile("[:=]")
+
+// mapEntryValidator validates every KEY=VALUE entry given to a map flag using expression with key and value set
+func mapEntryValidator(expression string) fisk.OptionValidator {
+	return func(entry string) error {
+		parts := mapEntrySeparator.Split(entry, 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("expected KEY=VALUE got '%s'", entry)
+		}
+
+		ok, err := validator.Validate(map[string]any{"key": parts[0], "Key": parts[0], "value": parts[1], "Value": parts[1]}, expression)
+		if err != nil {
+			return fmt.Errorf("validation using %q failed: %w", expression, err)
+		}
+
+		if !ok {
+			return fmt.Errorf("validation of %q using %q did not pass", parts[0], expression)
+		}
+
+		return nil
+	}
+}
+
 // CreateGenericCommand can be used to add all the typical flags and arguments etc if your command is based on GenericCommand. Values set in flags and arguments
 // are created on the supplied maps, if flags or arguments is nil then this will not attempt to add defined flags. Use this if you wish to use GenericCommand as
 // a base for your own commands while perhaps using an extended argument set
 func CreateGenericCommand(app KingpinCommand, sc *GenericCommand, arguments map[string]any, flags map[string]any, b *AppBuilder, cb fisk.Action) *fisk.CmdClause {
+	// flags of the application and parent commands are added to every command
+	sc = b.withInherited(sc)
+
 	description := sc.Description
 	if len(sc.Secrets) > 0 {
 		description = fmt.Sprintf("%s\n\nRequires the 1Password CLI and an active session.", description)
 	}
+	if sc.Constraints != nil && sc.Constraints.help() != "" {
+		description = fmt.Sprintf("%s\n\n%s", description, sc.Constraints.help())
+	}
+
+	// required inputs that are prompted for when not given, added while creating the inputs below
+	var prompts []*promptInput
+
+	// records which flags were given on the command line, used to check constraints
+	setByUser := map[string]*bool{}
 
-	cmd := app.Command(sc.Name, description).Action(runWrapper(*sc, arguments, flags, b, cb))
+	// flags whose configuration values and defaults are resolved once parsed
+	var inputs []*flagInput
+
+	cmd := app.Command(sc.Name, description).Action(func(pc *fisk.ParseContext) error {
+		err := b.resolveFlags(inputs)
+		if err != nil {
+			return err
+		}
+
+		return b.promptMissing(pc, prompts)
+	}).Action(runWrapper(*sc, arguments, flags, setByUser, b, cb))
 	for _, a := range sc.Aliases {
 		cmd.Alias(a)
 	}
@@ -307,28 +516,74 @@ func CreateGenericCommand(app KingpinCommand, sc *GenericCommand, arguments map[
 	}
 
 	if arguments != nil {
+		// fisk does not allow required arguments after optional ones, so once an argument is prompted for all
+		// following required arguments are too
+		var promptArgs bool
+
 		for _, a := range sc.Arguments {
 			arg := cmd.Arg(a.Name, a.Description)
-			if a.Required {
+			prompt := b.shouldPrompt(a.Prompt || promptArgs, a.Required, a.Default)
+			if a.Required && !prompt && !promptArgs {
 				arg.Required()
 			}
+			promptArgs = promptArgs || prompt
 
 			if a.Default != nil {
-				arg.Default(fmt.Sprintf("%v", a.Default))
+				arg.Default(defaultValues(a.Default)...)
 			}
 
+			var check fisk.OptionValidator
 			if a.ValidationExpression != "" {
-				arg.Validator(validator.FiskValidator(a.ValidationExpression))
+				check = validator.FiskValidator(a.ValidationExpression)
+				arg.Validator(check)
+			}
+
+			dType := normalizeType(a.Type)
+
+			switch {
+			case a.EnumFrom != nil:
+				arguments[a.Name] = b.applyEnumFrom(arg, a.EnumFrom, a.Multiple)
+			case isParsedInputType(dType):
+				arguments[a.Name] = applyParsedInputType(arg, dType, a.Layout, a.Multiple)
+			default:
+				arguments[a.Name] = applyInputType(arg, dType, a.Enum, a.Default, a.Multiple)
+			}
+
+			if prompt {
+				prompts = append(prompts, &promptInput{
+					name:        a.Name,
+					description: a.Description,
+					clause:      arg,
+					value:       arg.Model().Value,
+					dType:       dType,
+					enum:        a.Enum,
+					enumFrom:    a.EnumFrom,
+					multiple:    a.Multiple,
+					check:       check,
+				})
 			}
 
-			arguments[a.Name] = applyInputType(arg, normalizeType(a.Type), a.Enum, a.Default)
+			switch {
+			case a.Complete != nil:
+				arg.HintAction(b.completionHintAction(a.Complete))
+			case a.EnumFrom != nil:
+				arg.HintAction(b.enumHintAction(a.EnumFrom))
+			case len(a.Enum) > 0:
+				// fisk only offers enum values of flags during completion
+				arg.HintOptions(a.Enum...)
+			}
 		}
 	}
 
 	if flags != nil {
 		for _, f := range sc.Flags {
 			flag := cmd.Flag(f.Name, f.Description)
-			if f.Required {
+			setByUser[f.Name] = new(bool)
+			flag.IsSetByUser(setByUser[f.Name])
+
+			prompt := b.shouldPrompt(f.Prompt, f.Required, f.Default)
+			// required flags with a configuration key are checked once the configuration is resolved
+			if f.Required && !prompt && f.Config == "" {
 				flag.Required()
 			}
 
@@ -337,7 +592,7 @@ func CreateGenericCommand(app KingpinCommand, sc *GenericCommand, arguments map[
 			}
 
 			if f.Default != nil {
-				flag.Default(fmt.Sprintf("%v", f.Default))
+				flag.Default(defaultValues(f.Default)...)
 			}
 
 			if f.EnvVar != "" {
@@ -348,16 +603,66 @@ func CreateGenericCommand(app KingpinCommand, sc *GenericCommand, arguments map[
 				flag.Short([]rune(f.Short)[0])
 			}
 
-			if f.ValidationExpression != "" {
-				flag.Validator(validator.FiskValidator(f.ValidationExpression))
-			}
-
 			dType := normalizeType(f.Type)
 			if f.Bool {
 				dType = "bool"
 			}
 
-			flags[f.Name] = applyInputType(flag, dType, f.Enum, f.Default)
+			var check fisk.OptionValidator
+			switch {
+			case f.ValidationExpression != "" && dType == "map":
+				check = mapEntryValidator(f.ValidationExpression)
+			case f.ValidationExpression != "":
+				check = validator.FiskValidator(f.ValidationExpression)
+			}
+			if check != nil {
+				flag.Validator(check)
+			}
+
+			switch {
+			case f.EnumFrom != nil:
+				flags[f.Name] = b.applyEnumFrom(flag, f.EnumFrom, f.Multiple)
+			case isParsedInputType(dType):
+				flags[f.Name] = applyParsedInputType(flag, dType, f.Layout, f.Multiple)
+			default:
+				flags[f.Name] = applyInputType(flag, dType, f.Enum, f.Default, f.Multiple)
+			}
+
+			// the model resolves completions so is used before adding hint actions that might run commands
+			value := flag.Model().Value
+
+			inputs = append(inputs, &flagInput{
+				flag:      f,
+				dType:     dType,
+				value:     value,
+				target:    flags[f.Name],
+				setByUser: setByUser[f.Name],
+				prompt:    prompt,
+				check:     check,
+			})
+
+			if prompt {
+				prompts = append(prompts, &promptInput{
+					name:        f.Name,
+					description: f.Description,
+					clause:      flag,
+					value:       value,
+					envVar:      f.EnvVar,
+					config:      f.Config,
+					dType:       dType,
+					enum:        f.Enum,
+					enumFrom:    f.EnumFrom,
+					multiple:    f.Multiple,
+					check:       check,
+				})
+			}
+
+			switch {
+			case f.Complete != nil:
+				flag.HintAction(b.completionHintAction(f.Complete))
+			case f.EnumFrom != nil:
+				flag.HintAction(b.enumHintAction(f.EnumFrom))
+			}
 		}
 	}
 
@@ -368,8 +673,17 @@ func CreateGenericCommand(app KingpinCommand, sc *GenericCommand, arguments map[
 	return cmd
 }
 
-func runWrapper(cmd GenericCommand, arguments map[string]any, flags map[string]any, b *AppBuilder, handler fisk.Action) fisk.Action {
+func runWrapper(cmd GenericCommand, arguments map[string]any, flags map[string]any, setByUser map[string]*bool, b *AppBuilder, handler fisk.Action) fisk.Action {
 	return func(pc *fisk.ParseContext) error {
+		if cmd.Constraints != nil {
+			err := cmd.Constraints.check(func(name string) bool {
+				return flagGiven(b.cfg, cmd.Flags, setByUser, name)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
 		f := dereferenceArgsOrFlags(flags)
 
 		// Reset first so a reused builder (library/test usage) never bleeds the previous
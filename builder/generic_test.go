@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/choria-io/fisk"
 	. "github.com/onsi/ginkgo/v2"
@@ -87,10 +88,163 @@ var _ = Describe("GenericCommand", func() {
 			Entry("float32", "float32", "*float32"),
 			Entry("counter", "counter", "*int"),
 			Entry("existing_file", "existing_file", "*string"),
+			Entry("map", "map", "*map[string]string"),
 			Entry("case insensitive and trimmed", " INT ", "*int"),
 			Entry("unknown falls back to string", "nope", "*string"),
 		)
 
+		DescribeTable("Should map multiple value types to the correct fisk value",
+			func(typ string, enum []string, expected string) {
+				flags := map[string]any{}
+				def.Flags = []GenericFlag{{Name: "f", Description: "help", Type: typ, Enum: enum, Multiple: true}}
+				CreateGenericCommand(fisk.New("app", "app"), def, nil, flags, nil, cb)
+				Expect(fmt.Sprintf("%T", flags["f"])).To(Equal(expected))
+			},
+			Entry("no type defaults to strings", "", nil, "*[]string"),
+			Entry("enum", "", []string{"a", "b"}, "*[]string"),
+			Entry("int", "int", nil, "*[]int"),
+			Entry("uint16", "uint16", nil, "*[]uint16"),
+			Entry("float", "float", nil, "*[]float64"),
+			Entry("existing_dir", "existing_dir", nil, "*[]string"),
+		)
+
+		It("Should accept multiple values and expose them as slices", func() {
+			b := &AppBuilder{ctx: context.Background(), log: NoopLogger{}, cfg: map[string]any{}}
+			args := map[string]any{}
+			flags := map[string]any{}
+			d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+			d.Arguments = []GenericArgument{{Name: "ports", Description: "help", Type: "int", Multiple: true, Default: []any{"80"}}}
+			d.Flags = []GenericFlag{
+				{Name: "tag", Description: "help", Multiple: true, ValidationExpression: "len(value) > 1"},
+				{Name: "zone", Description: "help", Multiple: true, EnumFrom: &EnumSource{Command: "echo a; echo b"}},
+			}
+			app := fisk.New("app", "app")
+			app.Terminate(func(int) {})
+			CreateGenericCommand(app, d, args, flags, b, cb)
+
+			_, err := app.Parse([]string{"ginkgo", "--tag", "web", "--tag", "db", "--zone", "a", "--zone", "b", "443", "8443"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*(flags["tag"].(*[]string))).To(Equal([]string{"web", "db"}))
+			Expect(*(flags["zone"].(*[]string))).To(Equal([]string{"a", "b"}))
+			Expect(*(args["ports"].(*[]int))).To(Equal([]int{443, 8443}))
+
+			res, err := b.RenderTemplate(`{{ range .Flags.tag }}{{ . }} {{ end }}{{ join "," .Arguments.ports }}`, args, flags, WithSprig())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal("web db 443,8443"))
+		})
+
+		It("Should apply list defaults and validate every value", func() {
+			build := func() (map[string]any, *fisk.Application) {
+				args := map[string]any{}
+				d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+				d.Arguments = []GenericArgument{{Name: "ports", Description: "help", Type: "int", Multiple: true, Default: []any{"80", "443"}, ValidationExpression: "value != '22'"}}
+				app := fisk.New("app", "app")
+				app.Terminate(func(int) {})
+				CreateGenericCommand(app, d, args, nil, &AppBuilder{log: NoopLogger{}, cfg: map[string]any{}}, cb)
+				return args, app
+			}
+
+			args, app := build()
+			_, err := app.Parse([]string{"ginkgo"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*(args["ports"].(*[]int))).To(Equal([]int{80, 443}))
+
+			_, app = build()
+			_, err = app.Parse([]string{"ginkgo", "80", "22"})
+			Expect(err).To(MatchError(ContainSubstring("ports: validation using")))
+		})
+
+		It("Should accept key value pairs for map flags and validate every entry", func() {
+			build := func() (map[string]any, *fisk.Application) {
+				flags := map[string]any{}
+				d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+				d.Flags = []GenericFlag{{Name: "label", Description: "help", Type: "map", Default: []any{"team=ops"}, ValidationExpression: `key matches "^[a-z]+$" && value != ""`}}
+				app := fisk.New("app", "app")
+				app.Terminate(func(int) {})
+				CreateGenericCommand(app, d, nil, flags, &AppBuilder{log: NoopLogger{}, cfg: map[string]any{}}, cb)
+				return flags, app
+			}
+
+			flags, app := build()
+			_, err := app.Parse([]string{"ginkgo"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dereferenceArgsOrFlags(flags)["label"]).To(Equal(map[string]string{"team": "ops"}))
+
+			flags, app = build()
+			_, err = app.Parse([]string{"ginkgo", "--label", "env=prod", "--label", "tier:web"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dereferenceArgsOrFlags(flags)["label"]).To(Equal(map[string]string{"env": "prod", "tier": "web"}))
+
+			_, app = build()
+			_, err = app.Parse([]string{"ginkgo", "--label", "env=prod", "--label", "Tier=web"})
+			Expect(err).To(MatchError(ContainSubstring(`validation of "Tier" using`)))
+
+			_, app = build()
+			_, err = app.Parse([]string{"ginkgo", "--label", "env"})
+			Expect(err).To(MatchError(ContainSubstring("expected KEY=VALUE got 'env'")))
+		})
+
+		DescribeTable("Should parse rich types and expose them to templates",
+			func(typ string, layout string, input string, tmpl string, expected string) {
+				b := &AppBuilder{ctx: context.Background(), log: NoopLogger{}, cfg: map[string]any{}}
+				flags := map[string]any{}
+				d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+				d.Flags = []GenericFlag{{Name: "f", Description: "help", Type: typ, Layout: layout}}
+				app := fisk.New("app", "app")
+				app.Terminate(func(int) {})
+				CreateGenericCommand(app, d, nil, flags, b, cb)
+
+				_, err := app.Parse([]string{"ginkgo", "--f", input})
+				Expect(err).ToNot(HaveOccurred())
+
+				res, err := b.RenderTemplate(tmpl, nil, flags)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res).To(Equal(expected))
+			},
+			Entry("duration", "duration", "", "1m30s", "{{ .Flags.f.Seconds }}", "90"),
+			Entry("duration in days", "duration", "", "1d", "{{ .Flags.f.Hours }}", "24"),
+			Entry("url", "url", "", "https://example.net:8443/x?y=1", "{{ .Flags.f.Hostname }} {{ .Flags.f.Port }} {{ .Flags.f.Path }}", "example.net 8443 /x"),
+			Entry("ip", "ip", "", "192.168.1.1", "{{ .Flags.f }} {{ .Flags.f.IsPrivate }}", "192.168.1.1 true"),
+			Entry("cidr", "cidr", "", "10.0.0.1/8", "{{ .Flags.f }} {{ .Flags.f.IP }}", "10.0.0.0/8 10.0.0.0"),
+			Entry("regexp", "regexp", "", "^a+$", `{{ .Flags.f.MatchString "aaa" }}`, "true"),
+			Entry("json", "json", "", `{"a":[1,2]}`, "{{ index .Flags.f.a 1 }}", "2"),
+			Entry("date", "date", "", "2026-10-19", "{{ .Flags.f.Year }} {{ .Flags.f.YearDay }}", "2026 292"),
+			Entry("date with layout", "date", "02/01/2006", "19/10/2026", `{{ .Flags.f.Format "2006-01-02" }}`, "2026-10-19"),
+		)
+
+		DescribeTable("Should reject invalid rich type values",
+			func(typ string, input string, expected string) {
+				d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+				d.Arguments = []GenericArgument{{Name: "a", Description: "help", Type: typ}}
+				app := fisk.New("app", "app")
+				app.Terminate(func(int) {})
+				CreateGenericCommand(app, d, map[string]any{}, nil, &AppBuilder{log: NoopLogger{}, cfg: map[string]any{}}, cb)
+
+				_, err := app.Parse([]string{"ginkgo", input})
+				Expect(err).To(MatchError(ContainSubstring(expected)))
+			},
+			Entry("duration", "duration", "soon", "invalid duration 'soon'"),
+			Entry("url", "url", "example.net", "invalid url 'example.net'"),
+			Entry("ip", "ip", "1.2.3", "invalid ip address '1.2.3'"),
+			Entry("cidr", "cidr", "10.0.0.1", "invalid cidr '10.0.0.1'"),
+			Entry("regexp", "regexp", "a(", "invalid regular expression 'a('"),
+			Entry("json", "json", "{", "invalid json"),
+			Entry("date", "date", "yesterday", "invalid date 'yesterday'"),
+		)
+
+		It("Should accept multiple rich type values", func() {
+			args := map[string]any{}
+			d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+			d.Arguments = []GenericArgument{{Name: "timeouts", Description: "help", Type: "duration", Multiple: true}}
+			app := fisk.New("app", "app")
+			app.Terminate(func(int) {})
+			CreateGenericCommand(app, d, args, nil, &AppBuilder{log: NoopLogger{}, cfg: map[string]any{}}, cb)
+
+			_, err := app.Parse([]string{"ginkgo", "1s", "1m"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dereferenceArgsOrFlags(args)["timeouts"]).To(Equal([]any{time.Second, time.Minute}))
+		})
+
 		It("Should honor the legacy bool field over a set type", func() {
 			flags := map[string]any{}
 			def.Flags = []GenericFlag{{Name: "f", Description: "help", Bool: true, Type: "int"}}
@@ -121,7 +275,7 @@ var _ = Describe("GenericCommand", func() {
 				d.Flags = []GenericFlag{{Name: "count", Description: "help", Type: "int", Default: "3"}}
 				app := fisk.New("app", "app")
 				app.Terminate(func(int) {})
-				CreateGenericCommand(app, d, nil, flags, &AppBuilder{cfg: map[string]any{}}, cb)
+				CreateGenericCommand(app, d, nil, flags, &AppBuilder{log: NoopLogger{}, cfg: map[string]any{}}, cb)
 				return flags, app
 			}
 
@@ -139,6 +293,48 @@ var _ = Describe("GenericCommand", func() {
 			_, err = app.Parse([]string{"ginkgo", "--count", "abc"})
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("Should check values against enums found in the configuration and by commands", func() {
+			b := &AppBuilder{ctx: context.Background(), log: NoopLogger{}, cfg: map[string]any{
+				"deploy": map[string]any{"environments": []any{"dev", "prod"}},
+			}}
+
+			build := func() (map[string]any, map[string]any, *fisk.Application) {
+				args := map[string]any{}
+				flags := map[string]any{}
+				d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+				d.Arguments = []GenericArgument{{Name: "region", Description: "help", EnumFrom: &EnumSource{Command: "echo eu; echo us"}}}
+				d.Flags = []GenericFlag{{Name: "env", Description: "help", Default: "dev", EnumFrom: &EnumSource{Config: "deploy.environments"}}}
+				app := fisk.New("app", "app")
+				app.Terminate(func(int) {})
+				CreateGenericCommand(app, d, args, flags, b, cb)
+				return args, flags, app
+			}
+
+			args, flags, app := build()
+			_, err := app.Parse([]string{"ginkgo", "us"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*(args["region"].(*string))).To(Equal("us"))
+			Expect(*(flags["env"].(*string))).To(Equal("dev"))
+
+			_, flags, app = build()
+			_, err = app.Parse([]string{"ginkgo", "--env", "prod"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*(flags["env"].(*string))).To(Equal("prod"))
+
+			_, _, app = build()
+			_, err = app.Parse([]string{"ginkgo", "--env", "staging"})
+			Expect(err).To(MatchError(ContainSubstring("enum value must be one of dev,prod, got 'staging'")))
+
+			_, _, app = build()
+			_, err = app.Parse([]string{"ginkgo", "af"})
+			Expect(err).To(MatchError(ContainSubstring("enum value must be one of eu,us, got 'af'")))
+
+			b.cfg = map[string]any{}
+			_, _, app = build()
+			_, err = app.Parse([]string{"ginkgo"})
+			Expect(err).To(MatchError(ContainSubstring(`could not determine valid values: configuration key "deploy.environments" not found`)))
+		})
 	})
 
 	Describe("Validate", func() {
@@ -173,7 +369,7 @@ var _ = Describe("GenericCommand", func() {
 				d.Flags = []GenericFlag{{Name: "x", Description: "h", Type: "integr"}}
 			})
 			Expect(err).To(MatchError(ContainSubstring(`flag "x" has unknown type "integr"`)))
-			Expect(err).To(MatchError(ContainSubstring("valid types are: bool, counter")))
+			Expect(err).To(MatchError(ContainSubstring("valid types are: bool, cidr, counter")))
 		})
 
 		It("Should reject non string or bool defaults with a usable quoting hint", func() {
@@ -213,6 +409,84 @@ var _ = Describe("GenericCommand", func() {
 			})).To(MatchError(ContainSubstring(`sets both bool and type "int"`)))
 		})
 
+		It("Should validate enum_from", func() {
+			Expect(valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{
+					{Name: "c", Description: "h", EnumFrom: &EnumSource{Config: "envs"}},
+					{Name: "x", Description: "h", EnumFrom: &EnumSource{Command: "ls", Timeout: "1s"}},
+				}
+			})).To(Succeed())
+
+			err := valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{
+					{Name: "a", Description: "h", Enum: []string{"x"}, EnumFrom: &EnumSource{Config: "envs"}},
+					{Name: "b", Description: "h", EnumFrom: &EnumSource{}},
+					{Name: "c", Description: "h", EnumFrom: &EnumSource{Config: "envs", Command: "ls"}},
+					{Name: "d", Description: "h", Type: "int", EnumFrom: &EnumSource{Config: "envs"}},
+				}
+				d.Arguments = []GenericArgument{{Name: "e", Description: "h", EnumFrom: &EnumSource{Command: "ls", Timeout: "later"}}}
+			})
+			Expect(err).To(MatchError(ContainSubstring(`flag "a" sets both enum and enum_from, remove one`)))
+			Expect(err).To(MatchError(ContainSubstring("flag b: enum_from requires config or command")))
+			Expect(err).To(MatchError(ContainSubstring("flag c: enum_from can only set one of config or command")))
+			Expect(err).To(MatchError(ContainSubstring(`flag "d" sets both type and enum, remove one`)))
+			Expect(err).To(MatchError(ContainSubstring(`argument e: invalid enum_from timeout "later"`)))
+		})
+
+		It("Should validate multiple values", func() {
+			Expect(valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{
+					{Name: "t", Description: "h", Multiple: true, Default: []any{"a", "b"}},
+					{Name: "i", Description: "h", Type: "int", Multiple: true},
+				}
+				d.Arguments = []GenericArgument{
+					{Name: "a", Description: "h"},
+					{Name: "f", Description: "h", Type: "existing_file", Multiple: true},
+				}
+			})).To(Succeed())
+
+			err := valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{
+					{Name: "c", Description: "h", Type: "counter", Multiple: true},
+					{Name: "b", Description: "h", Bool: true, Multiple: true},
+					{Name: "l", Description: "h", Default: []any{"a"}},
+					{Name: "n", Description: "h", Multiple: true, Default: []any{float64(1)}},
+				}
+				d.Arguments = []GenericArgument{
+					{Name: "files", Description: "h", Multiple: true},
+					{Name: "last", Description: "h"},
+				}
+			})
+			Expect(err).To(MatchError(ContainSubstring(`flag "c" can not accept multiple values of type "counter"`)))
+			Expect(err).To(MatchError(ContainSubstring(`flag "b" can not accept multiple values of type "bool"`)))
+			Expect(err).To(MatchError(ContainSubstring(`flag "l" default can only be a list when accepting multiple values or of type map`)))
+			Expect(err).To(MatchError(ContainSubstring(`flag "n" default values must be strings, quote the value like "1"`)))
+			Expect(err).To(MatchError(ContainSubstring(`argument "files" accepts multiple values so must be the last argument`)))
+		})
+
+		It("Should only allow maps for flags", func() {
+			Expect(valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{{Name: "m", Description: "h", Type: "map", Default: []any{"a=b"}}}
+			})).To(Succeed())
+
+			err := valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{{Name: "m", Description: "h", Type: "map", Multiple: true}}
+				d.Arguments = []GenericArgument{{Name: "a", Description: "h", Type: "map"}}
+			})
+			Expect(err).To(MatchError(ContainSubstring(`flag "m" can not accept multiple values of type "map"`)))
+			Expect(err).To(MatchError(ContainSubstring(`argument "a" can not be of type map, only flags support maps`)))
+		})
+
+		It("Should only allow layouts for dates", func() {
+			Expect(valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{{Name: "d", Description: "h", Type: "date", Layout: "2006-01-02", Multiple: true}}
+			})).To(Succeed())
+
+			Expect(valErr(func(d *GenericCommand) {
+				d.Flags = []GenericFlag{{Name: "d", Description: "h", Type: "duration", Layout: "2006-01-02"}}
+			})).To(MatchError(`flag "d" sets a layout but is not of type date`))
+		})
+
 		It("Should validate arguments the same as flags", func() {
 			err := valErr(func(d *GenericCommand) {
 				d.Arguments = []GenericArgument{{Name: "a", Description: "h", Type: "nope", Default: float64(42)}}
@@ -249,13 +523,14 @@ var _ = Describe("GenericCommand", func() {
 			Expect(defaultHint("abc")).To(Equal("abc"))
 		})
 
-		It("knownTypeNames is sorted, includes bool and excludes duration", func() {
+		It("knownTypeNames is sorted and includes bool and parsed types", func() {
 			names := knownTypeNames()
 			Expect(sort.StringsAreSorted(names)).To(BeTrue())
 			Expect(names).To(ContainElement("bool"))
 			Expect(names).To(ContainElement("string"))
 			Expect(names).To(ContainElement("int"))
-			Expect(names).ToNot(ContainElement("duration"))
+			Expect(names).To(ContainElement("duration"))
+			Expect(names).ToNot(ContainElement("timestamp"))
 		})
 	})
 })
@@ -0,0 +1,452 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Include is a file to include, remote files from https:// or git+ssh://host/repo.git//path@ref locations must be
+// pinned to the sha256 checksum of their contents
+type Include struct {
+	// File is the path, glob pattern or remote location to include
+	File string `json:"file"`
+	// SHA256 is the expected checksum of a remote file
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// UnmarshalJSON accepts a file name or a file with a checksum
+func (i *Include) UnmarshalJSON(data []byte) error {
+	var file string
+	if json.Unmarshal(data, &file) == nil {
+		*i = Include{File: file}
+		return nil
+	}
+
+	type include Include
+	var inc include
+	err := json.Unmarshal(data, &inc)
+	if err != nil {
+		return fmt.Errorf("includes must be a file or a file with a checksum")
+	}
+
+	*i = Include(inc)
+
+	return nil
+}
+
+// IsRemote determines if the include is fetched from a remote location
+func (i Include) IsRemote() bool {
+	return isRemoteInclude(i.File)
+}
+
+// Includes are files to include, in definitions this can be a single file or a list of files. Relative paths are
+// relative to the file doing the including and entries can be glob patterns like commands/*.yaml
+type Includes []Include
+
+// UnmarshalJSON accepts a single include or a list of includes
+func (i *Includes) UnmarshalJSON(data []byte) error {
+	var list []Include
+	if json.Unmarshal(data, &list) == nil {
+		*i = list
+		return nil
+	}
+
+	var inc Include
+	err := json.Unmarshal(data, &inc)
+	if err != nil {
+		return fmt.Errorf("include_file must be a file or a list of files")
+	}
+
+	*i = nil
+	if inc.File != "" {
+		*i = Includes{inc}
+	}
+
+	return nil
+}
+
+// JSONSchema describes Includes in the application definition schema
+func (i Includes) JSONSchema() map[string]any {
+	include := map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{
+				"type":                 "object",
+				"required":             []string{"file"},
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"file":   map[string]any{"type": "string"},
+					"sha256": map[string]any{"type": "string", "pattern": "^[a-f0-9]{64}$"},
+				},
+			},
+		},
+	}
+
+	return map[string]any{
+		"oneOf": []any{
+			include,
+			map[string]any{"type": "array", "items": include},
+		},
+	}
+}
+
+// commandOrigin is where a command is defined, commands from included files are noted with the include that loaded them
+type commandOrigin struct {
+	// file is the file holding the command
+	file string
+	// path is the location of the command in file like $.commands[1]
+	path string
+	// includedFrom is the location of the include_file that loaded file, empty when not included
+	includedFrom string
+}
+
+// originKey temporarily stores the origin of commands while expanding includes, it is not valid JSON so can not clash
+var originKey = "\x00origin"
+
+// includeExpander loads included files into definitions, noting where every command was defined
+type includeExpander struct {
+	b       *AppBuilder
+	stack   []string
+	sources map[string]*definitionSource
+	unknown []unknownKey
+	// remote maps the cached copies of remote includes to their location
+	remote map[string]string
+	// update fetches remote includes ignoring their pins, noting the current checksums in updates
+	update  bool
+	updates []includeUpdate
+}
+
+func newIncludeExpander(b *AppBuilder) *includeExpander {
+	return &includeExpander{
+		b:       b,
+		sources: map[string]*definitionSource{},
+		remote:  map[string]string{},
+	}
+}
+
+// expandDefinition expands the includes of d, loaded from file, returning all commands with their includes expanded
+func (e *includeExpander) expandDefinition(d *Definition, file string, from string) ([]any, error) {
+	var cmds []any
+
+	for i, raw := range d.Commands {
+		var cmd any
+		err := json.Unmarshal(raw, &cmd)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+		}
+
+		if m, ok := cmd.(map[string]any); ok {
+			m[originKey] = commandOrigin{file: file, path: fmt.Sprintf("$.commands[%d]", i), includedFrom: from}
+
+			err = e.expandCommand(m, file, fmt.Sprintf("$.commands[%d]", i), from)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		cmds = append(cmds, cmd)
+	}
+
+	if len(d.IncludeFile) == 0 {
+		return cmds, nil
+	}
+
+	files, err := e.resolve(d.IncludeFile, file)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := e.location(file, "$.include_file")
+
+	for _, i := range files {
+		f, j, err := e.load(i, file)
+		if err != nil {
+			return nil, err
+		}
+
+		inc := &Definition{}
+		err = json.Unmarshal(j, inc)
+		if err != nil {
+			e.pop()
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, f, err)
+		}
+
+		unknown, err := findUnknownKeys(j, reflect.TypeOf(inc))
+		if err != nil {
+			e.pop()
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, f, err)
+		}
+		for _, k := range unknown {
+			k.file = f
+			e.unknown = append(e.unknown, k)
+		}
+
+		incCmds, err := e.expandDefinition(inc, f, loc)
+		e.pop()
+		if err != nil {
+			return nil, err
+		}
+
+		d.mergeIncluded(inc)
+		cmds = append(cmds, incCmds...)
+	}
+
+	return cmds, nil
+}
+
+// mergeIncluded sets settings from an included definition, these override those of d except for the name, description,
+// version and author which are only set when d does not set them
+func (d *Definition) mergeIncluded(inc *Definition) {
+	if d.Name == "" {
+		d.Name = inc.Name
+	}
+	if d.Description == "" {
+		d.Description = inc.Description
+	}
+	if d.Version == "" {
+		d.Version = inc.Version
+	}
+	if d.Author == "" {
+		d.Author = inc.Author
+	}
+	if inc.HelpTemplate != "" {
+		d.HelpTemplate = inc.HelpTemplate
+	}
+	if inc.Cheats != nil {
+		d.Cheats = inc.Cheats
+	}
+	if inc.Lint != nil {
+		d.Lint = inc.Lint
+	}
+	for name, t := range inc.Templates {
+		if d.Templates == nil {
+			d.Templates = map[string]*CommandTemplate{}
+		}
+		d.Templates[name] = t
+	}
+	if inc.AllowUnknownKeys {
+		d.AllowUnknownKeys = true
+	}
+	if inc.PromptMissing {
+		d.PromptMissing = true
+	}
+	d.Flags = append(d.Flags, inc.Flags...)
+}
+
+// expandCommand expands the include_file of cmd, found at path in file, and of all its sub commands. Settings from
+// included files override those of cmd except its name and included commands are added to those of cmd
+func (e *includeExpander) expandCommand(cmd map[string]any, file string, path string, from string) error {
+	subs, _ := cmd["commands"].([]any)
+	for i, sub := range subs {
+		m, ok := sub.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		subPath := fmt.Sprintf("%s.commands[%d]", path, i)
+		m[originKey] = commandOrigin{file: file, path: subPath, includedFrom: from}
+
+		err := e.expandCommand(m, file, subPath, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	includes, ok := cmd["include_file"]
+	if !ok {
+		return nil
+	}
+	delete(cmd, "include_file")
+
+	j, err := json.Marshal(includes)
+	if err != nil {
+		return err
+	}
+
+	var patterns Includes
+	err = json.Unmarshal(j, &patterns)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+
+	files, err := e.resolve(patterns, file)
+	if err != nil {
+		return err
+	}
+
+	loc := e.location(file, path+".include_file")
+
+	for _, i := range files {
+		f, j, err := e.load(i, file)
+		if err != nil {
+			return err
+		}
+
+		var inc map[string]any
+		err = json.Unmarshal(j, &inc)
+		if err != nil {
+			e.pop()
+			return fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, f, err)
+		}
+
+		err = e.expandCommand(inc, f, "$", loc)
+		e.pop()
+		if err != nil {
+			return err
+		}
+
+		for k, v := range inc {
+			switch k {
+			case "name", "commands", originKey:
+			default:
+				cmd[k] = v
+			}
+		}
+
+		incSubs, _ := inc["commands"].([]any)
+		subs = append(subs, incSubs...)
+	}
+
+	cmd["commands"] = subs
+
+	return nil
+}
+
+// resolve finds the files matching patterns relative to the directory holding file
+func (e *includeExpander) resolve(patterns Includes, file string) (Includes, error) {
+	var files Includes
+
+	_, fromRemote := e.remote[file]
+
+	for _, inc := range patterns {
+		if inc.IsRemote() {
+			files = append(files, inc)
+			continue
+		}
+
+		if fromRemote {
+			return nil, fmt.Errorf("%w: %s: remote files can only include other remote files", ErrInvalidDefinition, e.remote[file])
+		}
+
+		pattern := inc.File
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(file), pattern)
+		}
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			files = append(files, Include{File: pattern})
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid include %s: %v", ErrInvalidDefinition, pattern, err)
+		}
+		sort.Strings(matches)
+
+		for _, match := range matches {
+			files = append(files, Include{File: match})
+		}
+	}
+
+	return files, nil
+}
+
+// load reads inc, included by from, as JSON and notes it as being included until pop() is called, fails on include
+// cycles. The file the data was read from is returned, for remote includes this is the cached copy.
+func (e *includeExpander) load(inc Include, from string) (string, []byte, error) {
+	key := inc.File
+	if !inc.IsRemote() {
+		abs, err := filepath.Abs(inc.File)
+		if err != nil {
+			return "", nil, err
+		}
+		key = abs
+	}
+
+	for i, f := range e.stack {
+		if f == key {
+			chain := append(append([]string{}, e.stack[i:]...), key)
+			return "", nil, fmt.Errorf("%w: include cycle %s", ErrInvalidDefinition, strings.Join(chain, " -> "))
+		}
+	}
+
+	if e.b.log != nil {
+		e.b.log.Debugf("Including %s", inc.File)
+	}
+
+	var (
+		file = inc.File
+		y    []byte
+		err  error
+	)
+
+	if inc.IsRemote() {
+		file, y, err = e.loadRemote(inc, from)
+	} else {
+		y, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, inc.File, err)
+	}
+
+	e.stack = append(e.stack, key)
+
+	return file, j, nil
+}
+
+func (e *includeExpander) pop() {
+	e.stack = e.stack[:len(e.stack)-1]
+}
+
+// location is the file:line:col of path in file, just the file when the position is unknown
+func (e *includeExpander) location(file string, path string) string {
+	src, ok := e.sources[file]
+	if !ok {
+		src, _ = newDefinitionSource(file)
+		e.sources[file] = src
+	}
+
+	if loc := src.location(path); loc != "" {
+		return loc
+	}
+
+	return file
+}
+
+// collectOrigins removes the origins noted in cmds while expanding, returning them keyed by their path in the
+// expanded definition
+func collectOrigins(cmds []any, path string, origins map[string]commandOrigin) {
+	for i, cmd := range cmds {
+		m, ok := cmd.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		cmdPath := fmt.Sprintf("%s[%d]", path, i)
+
+		if o, ok := m[originKey].(commandOrigin); ok {
+			origins[cmdPath] = o
+			delete(m, originKey)
+		}
+
+		subs, _ := m["commands"].([]any)
+		collectOrigins(subs, cmdPath+".commands", origins)
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// pinEdit changes a line of a definition file to pin an include
+type pinEdit struct {
+	// line is the 1 based line being edited
+	line int
+	// column is the 1 based column of the include on line, before and after are added around the rest of the line
+	// from there. When 0 after is added as a new line after line.
+	column int
+	before string
+	after  string
+	// include is the include being pinned
+	include string
+}
+
+// pinIncludes adds checksums from pins, keyed by include location, to the unpinned block style includes in file. The
+// locations of the includes that were pinned are returned, includes in flow style are left for the user to pin.
+func pinIncludes(file string, pins map[string]string) (map[string]bool, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parser.ParseBytes(body, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []pinEdit
+	for _, doc := range f.Docs {
+		ast.Walk(pinVisitor(func(mv *ast.MappingValueNode) {
+			edits = append(edits, includePinEdits(mv, pins)...)
+		}), doc)
+	}
+
+	if len(edits) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].line > edits[j].line })
+
+	pinned := map[string]bool{}
+	lines := strings.Split(string(body), "\n")
+
+	for _, e := range edits {
+		switch {
+		case e.line > len(lines):
+			continue
+		case e.column == 0:
+			lines = append(lines[:e.line], append([]string{e.after}, lines[e.line:]...)...)
+		case e.column-1 <= len(lines[e.line-1]):
+			line := lines[e.line-1]
+			lines[e.line-1] = line[:e.column-1] + e.before + line[e.column-1:] + e.after
+		default:
+			continue
+		}
+
+		pinned[e.include] = true
+	}
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(file, []byte(strings.Join(lines, "\n")), stat.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	return pinned, nil
+}
+
+// repinEdit changes the sha256 setting of a pinned include
+type repinEdit struct {
+	// value is the current checksum in the definition
+	value *ast.StringNode
+	// sha256 is the new checksum
+	sha256 string
+}
+
+// repinIncludes replaces the checksums of the pinned includes in file with those in pins, keyed by include location.
+// Only the sha256 setting of each include is changed, the same checksum elsewhere in file is left as is.
+func repinIncludes(file string, pins map[string]string) error {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	f, err := parser.ParseBytes(body, 0)
+	if err != nil {
+		return err
+	}
+
+	var edits []repinEdit
+	for _, doc := range f.Docs {
+		ast.Walk(pinVisitor(func(mv *ast.MappingValueNode) {
+			edits = append(edits, includeRepinEdits(mv, pins)...)
+		}), doc)
+	}
+
+	if len(edits) == 0 {
+		return nil
+	}
+
+	// edits are done from the end so earlier positions stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		pi, pj := edits[i].value.Token.Position, edits[j].value.Token.Position
+		if pi.Line == pj.Line {
+			return pi.Column > pj.Column
+		}
+		return pi.Line > pj.Line
+	})
+
+	lines := strings.Split(string(body), "\n")
+
+	for _, e := range edits {
+		pos := e.value.Token.Position
+		if pos.Line > len(lines) || pos.Column-1 > len(lines[pos.Line-1]) {
+			continue
+		}
+
+		line := lines[pos.Line-1]
+		rest := line[pos.Column-1:]
+		idx := strings.Index(rest, e.value.Value)
+		if idx == -1 {
+			continue
+		}
+
+		lines[pos.Line-1] = line[:pos.Column-1] + rest[:idx] + e.sha256 + rest[idx+len(e.value.Value):]
+	}
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, []byte(strings.Join(lines, "\n")), stat.Mode())
+}
+
+// includeRepinEdits are the edits needed to change the checksums of the includes in mv when it is an include_file
+// setting
+func includeRepinEdits(mv *ast.MappingValueNode, pins map[string]string) []repinEdit {
+	if mv.Key == nil || mv.Key.String() != "include_file" {
+		return nil
+	}
+
+	items := []ast.Node{mv.Value}
+	if seq, ok := mv.Value.(*ast.SequenceNode); ok {
+		items = seq.Values
+	}
+
+	var edits []repinEdit
+
+	for _, item := range items {
+		var values []*ast.MappingValueNode
+
+		switch m := item.(type) {
+		case *ast.MappingNode:
+			values = m.Values
+		case *ast.MappingValueNode:
+			values = []*ast.MappingValueNode{m}
+		default:
+			continue
+		}
+
+		var file, sum *ast.StringNode
+		for _, v := range values {
+			s, ok := v.Value.(*ast.StringNode)
+			if !ok {
+				continue
+			}
+
+			switch v.Key.String() {
+			case "file":
+				file = s
+			case "sha256":
+				sum = s
+			}
+		}
+
+		if file == nil || sum == nil {
+			continue
+		}
+
+		pin, ok := pins[file.Value]
+		if !ok || pin == sum.Value {
+			continue
+		}
+
+		edits = append(edits, repinEdit{value: sum, sha256: pin})
+	}
+
+	return edits
+}
+
+// pinVisitor calls the function for every mapping value in a document
+type pinVisitor func(*ast.MappingValueNode)
+
+func (v pinVisitor) Visit(n ast.Node) ast.Visitor {
+	if mv, ok := n.(*ast.MappingValueNode); ok {
+		v(mv)
+	}
+
+	return v
+}
+
+// includePinEdits are the edits needed to pin the includes in mv when it is an include_file setting
+func includePinEdits(mv *ast.MappingValueNode, pins map[string]string) []pinEdit {
+	if mv.Key == nil || mv.Key.String() != "include_file" {
+		return nil
+	}
+
+	var edits []pinEdit
+
+	switch v := mv.Value.(type) {
+	case *ast.StringNode:
+		sum, ok := pins[v.Value]
+		if !ok {
+			return nil
+		}
+
+		// a value on the line of the key moves to a new line below it, otherwise it stays where it is
+		pos := v.Token.Position
+		before := "- file: "
+		indent := strings.Repeat(" ", pos.Column-1)
+		if pos.Line == mv.Key.GetToken().Position.Line {
+			indent = strings.Repeat(" ", mv.Key.GetToken().Position.Column+1)
+			before = "\n" + indent + before
+		}
+
+		edits = append(edits, pinEdit{
+			line:    pos.Line,
+			column:  pos.Column,
+			before:  before,
+			after:   "\n" + indent + "  sha256: " + sum,
+			include: v.Value,
+		})
+
+	case *ast.SequenceNode:
+		if v.IsFlowStyle {
+			return nil
+		}
+
+		for _, item := range v.Values {
+			switch i := item.(type) {
+			case *ast.StringNode:
+				sum, ok := pins[i.Value]
+				if !ok {
+					continue
+				}
+
+				edits = append(edits, pinEdit{
+					line:    i.Token.Position.Line,
+					column:  i.Token.Position.Column,
+					before:  "file: ",
+					after:   "\n" + strings.Repeat(" ", i.Token.Position.Column-1) + "sha256: " + sum,
+					include: i.Value,
+				})
+
+			default:
+				edits = append(edits, mappingPinEdits(item, pins)...)
+			}
+		}
+
+	default:
+		edits = append(edits, mappingPinEdits(mv.Value, pins)...)
+	}
+
+	return edits
+}
+
+// mappingPinEdits adds a sha256 to an include written like file: x without a checksum
+func mappingPinEdits(n ast.Node, pins map[string]string) []pinEdit {
+	var values []*ast.MappingValueNode
+
+	switch m := n.(type) {
+	case *ast.MappingNode:
+		if m.IsFlowStyle {
+			return nil
+		}
+		values = m.Values
+	case *ast.MappingValueNode:
+		values = []*ast.MappingValueNode{m}
+	default:
+		return nil
+	}
+
+	var file *ast.MappingValueNode
+	for _, v := range values {
+		switch v.Key.String() {
+		case "sha256":
+			return nil
+		case "file":
+			file = v
+		}
+	}
+
+	if file == nil {
+		return nil
+	}
+
+	s, ok := file.Value.(*ast.StringNode)
+	if !ok {
+		return nil
+	}
+
+	sum, ok := pins[s.Value]
+	if !ok {
+		return nil
+	}
+
+	key := file.Key.GetToken().Position
+
+	return []pinEdit{{
+		line:    key.Line,
+		after:   strings.Repeat(" ", key.Column-1) + "sha256: " + sum,
+		include: s.Value,
+	}}
+}
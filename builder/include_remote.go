@@ -0,0 +1,305 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+var (
+	// includeHTTPClient fetches https:// includes
+	includeHTTPClient = &http.Client{Timeout: time.Minute}
+
+	// maxIncludeSize is the largest remote include that will be fetched
+	maxIncludeSize int64 = 10 * 1024 * 1024
+)
+
+// includeUpdate is the current checksum of a remote include found by update-includes
+type includeUpdate struct {
+	// file is the file holding the include, for includes in remote files this is their location
+	file string
+	// remote indicates file is a remote include that can not be updated locally
+	remote bool
+	// include is the include as found in file
+	include Include
+	// sha256 is the checksum of the current content of the include
+	sha256 string
+	// pinned indicates the include had no checksum and was pinned to sha256
+	pinned bool
+}
+
+// isRemoteInclude determines if file is fetched from a remote location rather than read from disk
+func isRemoteInclude(file string) bool {
+	return strings.HasPrefix(file, "https://") || strings.HasPrefix(file, "git+")
+}
+
+// loadRemote reads the remote include inc, included by from, from the cache fetching it when not cached. The content
+// must match the checksum pinned in the include unless updating.
+func (e *includeExpander) loadRemote(inc Include, from string) (string, []byte, error) {
+	if !e.update {
+		if inc.SHA256 == "" {
+			return "", nil, fmt.Errorf("%w: remote include %s has no sha256 checksum, run appbuilder update-includes to pin it", ErrInvalidDefinition, inc.File)
+		}
+
+		cached := e.cachePath(inc.SHA256)
+		body, err := os.ReadFile(cached)
+		if err == nil && checksum(body) == inc.SHA256 {
+			e.remote[cached] = inc.File
+			return cached, body, nil
+		}
+	}
+
+	if e.b.log != nil {
+		e.b.log.Debugf("Fetching remote include %s", inc.File)
+	}
+
+	body, err := fetchRemoteInclude(e.b.ctx, inc.File)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: could not fetch %s: %v", ErrInvalidDefinition, inc.File, err)
+	}
+
+	sum := checksum(body)
+
+	if e.update {
+		e.updates = append(e.updates, includeUpdate{file: e.displayName(from), remote: e.isRemote(from), include: inc, sha256: sum})
+	} else if sum != inc.SHA256 {
+		return "", nil, fmt.Errorf("%w: remote include %s has checksum %s but %s is pinned, run appbuilder update-includes to accept the change", ErrInvalidDefinition, inc.File, sum, inc.SHA256)
+	}
+
+	cached := e.cachePath(sum)
+	err = os.MkdirAll(filepath.Dir(cached), 0700)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = os.WriteFile(cached, body, 0600)
+	if err != nil {
+		return "", nil, err
+	}
+
+	e.remote[cached] = inc.File
+
+	return cached, body, nil
+}
+
+func (e *includeExpander) cachePath(sum string) string {
+	return filepath.Join(e.b.includeCache, sum+".yaml")
+}
+
+func (e *includeExpander) isRemote(file string) bool {
+	_, ok := e.remote[file]
+	return ok
+}
+
+// displayName is the location of file, for cached remote includes this is where they were fetched from
+func (e *includeExpander) displayName(file string) string {
+	if remote, ok := e.remote[file]; ok {
+		return remote
+	}
+
+	return file
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchRemoteInclude fetches the content of a https:// or git+ include
+func fetchRemoteInclude(ctx context.Context, location string) ([]byte, error) {
+	if strings.HasPrefix(location, "git+") {
+		return fetchGitInclude(ctx, location)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := includeHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIncludeSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxIncludeSize {
+		return nil, fmt.Errorf("larger than %d bytes", maxIncludeSize)
+	}
+
+	return body, nil
+}
+
+// parseGitInclude parses locations like git+ssh://git@example.net/repo.git//path/file.yaml@ref into the repository,
+// the path within it and the ref which defaults to HEAD
+func parseGitInclude(location string) (repo string, path string, ref string, err error) {
+	u := strings.TrimPrefix(location, "git+")
+
+	scheme := strings.Index(u, "://")
+	if scheme == -1 {
+		return "", "", "", fmt.Errorf("invalid git include %s, expected git+ssh://host/repo.git//path@ref", location)
+	}
+
+	sep := strings.Index(u[scheme+3:], "//")
+	if sep == -1 {
+		return "", "", "", fmt.Errorf("invalid git include %s, no // separating the repository and path", location)
+	}
+
+	repo = u[:scheme+3+sep]
+	path = u[scheme+3+sep+2:]
+	ref = "HEAD"
+
+	if i := strings.LastIndex(path, "@"); i != -1 {
+		path, ref = path[:i], path[i+1:]
+	}
+
+	if repo == "" || path == "" || ref == "" {
+		return "", "", "", fmt.Errorf("invalid git include %s, expected git+ssh://host/repo.git//path@ref", location)
+	}
+
+	// these are passed to git, never allow them to be mistaken for options
+	if strings.HasPrefix(repo, "-") || strings.HasPrefix(path, "-") || strings.HasPrefix(ref, "-") {
+		return "", "", "", fmt.Errorf("invalid git include %s, the repository, path and ref can not start with -", location)
+	}
+
+	return repo, path, ref, nil
+}
+
+// fetchGitInclude does a shallow fetch of the ref in a git+ include and reads the file from it
+func fetchGitInclude(ctx context.Context, location string) ([]byte, error) {
+	repo, path, ref, err := parseGitInclude(location)
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := os.MkdirTemp("", "appbuilder-include")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(td)
+
+	git := func(args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", td}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+
+		return out, nil
+	}
+
+	_, err = git("init", "-q")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = git("fetch", "-q", "--depth", "1", "--", repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return git("show", "FETCH_HEAD:"+path)
+}
+
+// updateIncludes fetches all remote includes of the definition in path and pins their current checksums in the files
+// including them, includes found in remote files can not be updated. Includes without a checksum are pinned unless
+// written in flow style.
+func (b *AppBuilder) updateIncludes(path string) ([]includeUpdate, error) {
+	y, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+
+	d := &Definition{}
+	err = json.Unmarshal(j, d)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+
+	e := newIncludeExpander(b)
+	e.update = true
+	if abs, err := filepath.Abs(path); err == nil {
+		e.stack = append(e.stack, abs)
+	}
+
+	_, err = e.expandDefinition(d, path, "")
+	if err != nil {
+		return nil, err
+	}
+
+	unpinned := map[string]map[string]string{}
+	changed := map[string]map[string]string{}
+
+	for _, u := range e.updates {
+		var pins map[string]map[string]string
+
+		switch {
+		case u.remote || u.include.SHA256 == u.sha256:
+			continue
+		case u.include.SHA256 == "":
+			pins = unpinned
+		default:
+			pins = changed
+		}
+
+		if pins[u.file] == nil {
+			pins[u.file] = map[string]string{}
+		}
+		pins[u.file][u.include.File] = u.sha256
+	}
+
+	for file, pins := range changed {
+		err = repinIncludes(file, pins)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for file, pins := range unpinned {
+		pinned, err := pinIncludes(file, pins)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, u := range e.updates {
+			if u.file == file && !u.remote && u.include.SHA256 == "" && pinned[u.include.File] {
+				e.updates[i].pinned = true
+			}
+		}
+	}
+
+	return e.updates, nil
+}
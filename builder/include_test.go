@@ -0,0 +1,393 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+var _ = Describe("Includes", func() {
+	var td string
+
+	BeforeEach(func() {
+		registerTestCommand()
+		td = GinkgoT().TempDir()
+	})
+
+	Describe("UnmarshalJSON", func() {
+		It("Should accept a file or a list of files", func() {
+			var i Includes
+			Expect(json.Unmarshal([]byte(`"x.yaml"`), &i)).To(Succeed())
+			Expect(i).To(Equal(Includes{{File: "x.yaml"}}))
+
+			Expect(json.Unmarshal([]byte(`["x.yaml", "y/*.yaml"]`), &i)).To(Succeed())
+			Expect(i).To(Equal(Includes{{File: "x.yaml"}, {File: "y/*.yaml"}}))
+
+			Expect(json.Unmarshal([]byte(`{"file":"https://example.net/x.yaml","sha256":"abc"}`), &i)).To(Succeed())
+			Expect(i).To(Equal(Includes{{File: "https://example.net/x.yaml", SHA256: "abc"}}))
+
+			Expect(json.Unmarshal([]byte(`["x.yaml", {"file":"git+ssh://example.net/r.git//x.yaml"}]`), &i)).To(Succeed())
+			Expect(i).To(Equal(Includes{{File: "x.yaml"}, {File: "git+ssh://example.net/r.git//x.yaml"}}))
+
+			Expect(json.Unmarshal([]byte(`1`), &i)).To(MatchError("include_file must be a file or a list of files"))
+		})
+	})
+
+	It("Should include definitions and commands relative to the including file", func() {
+		writeTestFile(td, "app/test-app.yaml", `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+include_file:
+  - defs/base.yaml
+
+commands:
+  - name: local
+    description: local
+    type: test
+
+help_template: default
+`)
+		writeTestFile(td, "app/defs/base.yaml", `name: ignored
+description: ignored
+help_template: compact
+include_file: more.yaml
+commands:
+  - name: parent
+    description: parent
+    type: test
+    include_file: [commands/*.yaml, ../single.yaml]
+`)
+		writeTestFile(td, "app/defs/more.yaml", `commands:
+  - name: more
+    description: more
+    type: test
+`)
+		writeTestFile(td, "app/defs/commands/a.yaml", `name: ignored
+description: a
+type: test
+commands:
+  - name: a
+    description: a
+    type: test
+`)
+		writeTestFile(td, "app/defs/commands/b.yaml", `commands:
+  - name: b
+    description: b
+    type: test
+`)
+		writeTestFile(td, "app/single.yaml", `commands:
+  - name: single
+    description: single
+    type: test
+    include_file: defs/commands/b.yaml
+`)
+
+		_, d, err := loadTestDefinition(filepath.Join(td, "app/test-app.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Name).To(Equal("test"))
+		Expect(d.HelpTemplate).To(Equal("compact"))
+
+		var names []string
+		for _, c := range d.Commands {
+			names = append(names, gjson.GetBytes(c, "name").String())
+		}
+		Expect(names).To(Equal([]string{"local", "parent", "more"}))
+
+		parent := d.Commands[1]
+		Expect(gjson.GetBytes(parent, "include_file").Exists()).To(BeFalse())
+		Expect(gjson.GetBytes(parent, "description").String()).To(Equal("a"))
+		Expect(gjson.GetBytes(parent, "commands.#.name").String()).To(Equal(`["a","b","single"]`))
+		Expect(gjson.GetBytes(parent, "commands.2.commands.#.name").String()).To(Equal(`["b"]`))
+
+		base := filepath.Join(td, "app/defs/base.yaml")
+		Expect(d.origins["$.commands[0]"]).To(Equal(commandOrigin{file: filepath.Join(td, "app/test-app.yaml"), path: "$.commands[0]"}))
+		Expect(d.origins["$.commands[1]"]).To(Equal(commandOrigin{file: base, path: "$.commands[0]", includedFrom: filepath.Join(td, "app/test-app.yaml") + ":6:3"}))
+		Expect(d.origins["$.commands[1].commands[1]"]).To(Equal(commandOrigin{file: filepath.Join(td, "app/defs/commands/b.yaml"), path: "$.commands[0]", includedFrom: base + ":9:19"}))
+		Expect(d.origins["$.commands[1].commands[2].commands[0]"].includedFrom).To(Equal(filepath.Join(td, "app/single.yaml") + ":5:19"))
+	})
+
+	It("Should detect include cycles", func() {
+		writeTestFile(td, "test-app.yaml", `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+commands:
+  - name: parent
+    description: parent
+    type: test
+    include_file: a.yaml
+`)
+		writeTestFile(td, "a.yaml", `include_file: b.yaml`)
+		writeTestFile(td, "b.yaml", `include_file: a.yaml`)
+
+		_, _, err := loadTestDefinition(filepath.Join(td, "test-app.yaml"))
+		Expect(err).To(MatchError(fmt.Sprintf("invalid definition: include cycle %s -> %s -> %s", filepath.Join(td, "a.yaml"), filepath.Join(td, "b.yaml"), filepath.Join(td, "a.yaml"))))
+
+		writeTestFile(td, "test-app.yaml", `include_file: test-app.yaml`)
+		_, _, err = loadTestDefinition(filepath.Join(td, "test-app.yaml"))
+		Expect(err).To(MatchError(ContainSubstring("include cycle")))
+	})
+
+	It("Should show where failing commands were included from", func() {
+		writeTestFile(td, "test-app.yaml", `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+commands:
+  - name: parent
+    description: parent
+    type: test
+    include_file: sub.yaml
+`)
+		writeTestFile(td, "sub.yaml", `commands:
+  - name: sub
+    type: test
+    other: x
+`)
+
+		b, d, err := loadTestDefinition(filepath.Join(td, "test-app.yaml"), withDeferredUnknownKeys())
+		Expect(err).ToNot(HaveOccurred())
+
+		v := newDefinitionValidator(b)
+		v.validateDefinition(d)
+
+		sub := filepath.Join(td, "sub.yaml")
+		from := filepath.Join(td, "test-app.yaml") + ":9:19"
+		Expect(v.errs).To(Equal([]string{
+			fmt.Sprintf("%s:2:5: root -> parent (test) -> sub (test): description is required (included from %s)", sub, from),
+			fmt.Sprintf(`%s:4:5: root -> parent (test) -> sub (test): unknown key "other" (included from %s)`, sub, from),
+		}))
+	})
+	Describe("Remote includes", func() {
+		var (
+			srv     *httptest.Server
+			content string
+			b       *AppBuilder
+		)
+
+		const remoteCommands = `commands:
+  - name: remote
+    description: remote
+    type: test
+`
+
+		BeforeEach(func() {
+			content = remoteCommands
+			srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/commands.yaml" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, content)
+			}))
+			DeferCleanup(srv.Close)
+
+			client := includeHTTPClient
+			includeHTTPClient = srv.Client()
+			DeferCleanup(func() { includeHTTPClient = client })
+
+			var err error
+			b, err = New(context.Background(), "test", WithLogger(NoopLogger{}))
+			Expect(err).ToNot(HaveOccurred())
+			b.includeCache = filepath.Join(td, "cache")
+		})
+
+		definition := func(include string) string {
+			return fmt.Sprintf(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+commands:
+  - name: parent
+    description: parent
+    type: test
+    include_file:
+      %s
+`, include)
+		}
+
+		It("Should parse git locations", func() {
+			repo, path, ref, err := parseGitInclude("git+ssh://git@example.net/org/repo.git//defs/x.yaml@v1.0.0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(repo).To(Equal("ssh://git@example.net/org/repo.git"))
+			Expect(path).To(Equal("defs/x.yaml"))
+			Expect(ref).To(Equal("v1.0.0"))
+
+			repo, path, ref, err = parseGitInclude("git+file:///srv/repo.git//x.yaml")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(repo).To(Equal("file:///srv/repo.git"))
+			Expect(path).To(Equal("x.yaml"))
+			Expect(ref).To(Equal("HEAD"))
+
+			_, _, _, err = parseGitInclude("git+ssh://example.net/repo.git")
+			Expect(err).To(MatchError(ContainSubstring("no // separating the repository and path")))
+
+			_, _, _, err = parseGitInclude("git+ssh://example.net/repo.git//x.yaml@--upload-pack=touch /tmp/x")
+			Expect(err).To(MatchError(ContainSubstring("the repository, path and ref can not start with -")))
+
+			_, _, _, err = parseGitInclude("git+--upload-pack=x://example.net//x.yaml")
+			Expect(err).To(MatchError(ContainSubstring("the repository, path and ref can not start with -")))
+		})
+
+		It("Should require a pin", func() {
+			writeTestFile(td, "test-app.yaml", definition(`- `+srv.URL+`/commands.yaml`))
+
+			_, err := b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("has no sha256 checksum, run appbuilder update-includes to pin it")))
+		})
+
+		It("Should fetch, verify and cache pinned includes", func() {
+			writeTestFile(td, "test-app.yaml", definition(fmt.Sprintf(`- file: %s/commands.yaml
+        sha256: %s`, srv.URL, checksum([]byte(remoteCommands)))))
+
+			d, err := b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gjson.GetBytes(d.Commands[0], "commands.#.name").String()).To(Equal(`["remote"]`))
+			Expect(d.origins["$.commands[0].commands[0]"].file).To(Equal(filepath.Join(td, "cache", checksum([]byte(remoteCommands))+".yaml")))
+
+			// served from the cache even when the remote changed
+			content = "changed"
+			_, err = b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+
+			// fetched again and verified when the cache does not match
+			Expect(os.WriteFile(filepath.Join(td, "cache", checksum([]byte(remoteCommands))+".yaml"), []byte("x"), 0600)).To(Succeed())
+			_, err = b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("has checksum %s but %s is pinned", checksum([]byte("changed")), checksum([]byte(remoteCommands))))))
+		})
+
+		It("Should not allow remote files to include local files", func() {
+			content = "include_file: /etc/passwd\n"
+			writeTestFile(td, "test-app.yaml", definition(fmt.Sprintf(`- file: %s/commands.yaml
+        sha256: %s`, srv.URL, checksum([]byte(content)))))
+
+			_, err := b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("remote files can only include other remote files")))
+		})
+
+		It("Should update pins", func() {
+			old := checksum([]byte("old"))
+			writeTestFile(td, "test-app.yaml", definition(fmt.Sprintf(`- file: %s/commands.yaml
+        sha256: %s
+      - %s/commands.yaml`, srv.URL, old, srv.URL)))
+
+			updates, err := b.updateIncludes(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(2))
+			Expect(updates[0].include.SHA256).To(Equal(old))
+			Expect(updates[0].sha256).To(Equal(checksum([]byte(remoteCommands))))
+			Expect(updates[1].include.SHA256).To(BeEmpty())
+			Expect(updates[1].pinned).To(BeTrue())
+
+			def, err := os.ReadFile(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(def)).To(ContainSubstring(fmt.Sprintf(`- file: %s/commands.yaml
+        sha256: %s
+      - file: %s/commands.yaml
+        sha256: %s`, srv.URL, checksum([]byte(remoteCommands)), srv.URL, checksum([]byte(remoteCommands)))))
+			Expect(string(def)).ToNot(ContainSubstring(old))
+		})
+
+		It("Should only update the checksum of the include", func() {
+			old := checksum([]byte("old"))
+			def := strings.Replace(definition(fmt.Sprintf(`- {file: %s/commands.yaml, sha256: %s}`, srv.URL, old)), "description: parent", "description: parent "+old, 1)
+			writeTestFile(td, "test-app.yaml", def)
+
+			_, err := b.updateIncludes(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+
+			updated, err := os.ReadFile(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(updated)).To(Equal(strings.Replace(def, "sha256: "+old, "sha256: "+checksum([]byte(remoteCommands)), 1)))
+			Expect(string(updated)).To(ContainSubstring("description: parent " + old))
+		})
+
+		It("Should pin unpinned includes", func() {
+			writeTestFile(td, "test-app.yaml", definition(srv.URL+"/commands.yaml # remote"))
+
+			updates, err := b.updateIncludes(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].pinned).To(BeTrue())
+
+			d, err := b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gjson.GetBytes(d.Commands[0], "commands.#.name").String()).To(Equal(`["remote"]`))
+
+			out := bytes.NewBuffer([]byte{})
+			b.stdOut = out
+			b.appPath = filepath.Join(td, "test-app.yaml")
+			Expect(b.updateIncludesAction(nil)).To(Succeed())
+			Expect(out.String()).To(Equal(fmt.Sprintf("%s: %s/commands.yaml is up to date\n", b.appPath, srv.URL)))
+		})
+
+		It("Should pin includes in all block styles", func() {
+			location := srv.URL + "/commands.yaml"
+
+			for _, d := range []string{
+				definition(location),
+				strings.Replace(definition(location), "include_file:\n      ", "include_file: ", 1),
+				definition("file: " + location),
+				definition("- file: " + location),
+			} {
+				writeTestFile(td, "test-app.yaml", d)
+
+				updates, err := b.updateIncludes(filepath.Join(td, "test-app.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updates).To(HaveLen(1))
+				Expect(updates[0].pinned).To(BeTrue(), d)
+
+				_, err = b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+				Expect(err).ToNot(HaveOccurred(), d)
+			}
+		})
+
+		It("Should fetch includes from git repositories", func() {
+			git := func(dir string, args ...string) {
+				cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+				cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=ginkgo", "GIT_AUTHOR_EMAIL=ginkgo@example.net", "GIT_COMMITTER_NAME=ginkgo", "GIT_COMMITTER_EMAIL=ginkgo@example.net")
+				out, err := cmd.CombinedOutput()
+				Expect(err).ToNot(HaveOccurred(), string(out))
+			}
+
+			bare := filepath.Join(td, "repo.git")
+			work := filepath.Join(td, "work")
+			Expect(os.MkdirAll(work, 0700)).To(Succeed())
+			git(td, "init", "-q", "--bare", bare)
+			git(work, "init", "-q")
+			writeTestFile(td, "work/defs/commands.yaml", remoteCommands)
+			git(work, "add", "defs/commands.yaml")
+			git(work, "commit", "-q", "-m", "commands")
+			git(work, "tag", "v1")
+			git(work, "push", "-q", bare, "HEAD:refs/heads/main", "v1")
+
+			location := "git+file://" + filepath.ToSlash(bare) + "//defs/commands.yaml@v1"
+			if !strings.HasPrefix(bare, "/") {
+				location = "git+file:///" + filepath.ToSlash(bare) + "//defs/commands.yaml@v1"
+			}
+
+			writeTestFile(td, "test-app.yaml", definition(fmt.Sprintf(`- file: %s
+        sha256: %s`, location, checksum([]byte(remoteCommands)))))
+
+			d, err := b.loadDefinition(filepath.Join(td, "test-app.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gjson.GetBytes(d.Commands[0], "commands.#.name").String()).To(Equal(`["remote"]`))
+		})
+	})
+})
@@ -0,0 +1,108 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FlagInheritor is implemented by commands with sub commands whose flags are added to all commands below them
+type FlagInheritor interface {
+	InheritedFlags() []GenericFlag
+}
+
+// inheritedFlag is a flag of the application or a parent command that is added to all commands below it
+type inheritedFlag struct {
+	GenericFlag
+	// origin describes where the flag is defined
+	origin string
+}
+
+// withInheritedFlags runs fn with flags defined by origin added to the inherited flags
+func (b *AppBuilder) withInheritedFlags(origin string, flags []GenericFlag, fn func() error) error {
+	if len(flags) == 0 {
+		return fn()
+	}
+
+	prev := b.inheritedFlags
+	defer func() { b.inheritedFlags = prev }()
+
+	b.inheritedFlags = append([]inheritedFlag{}, prev...)
+	for _, f := range flags {
+		b.inheritedFlags = append(b.inheritedFlags, inheritedFlag{GenericFlag: f, origin: origin})
+	}
+
+	return fn()
+}
+
+// withCommandFlags runs fn with the flags c passes to its sub commands added to the inherited flags
+func (b *AppBuilder) withCommandFlags(c Command, fn func() error) error {
+	fi, ok := c.(FlagInheritor)
+	if !ok {
+		return fn()
+	}
+
+	return b.withInheritedFlags(c.String(), fi.InheritedFlags(), fn)
+}
+
+// inheritsFlag determines if name is a flag inherited by the command being created or validated
+func (b *AppBuilder) inheritsFlag(name string) bool {
+	for _, f := range b.inheritedFlags {
+		if f.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withInherited adds the inherited flags to the flags of cmd
+func (b *AppBuilder) withInherited(cmd *GenericCommand) *GenericCommand {
+	if b == nil || len(b.inheritedFlags) == 0 {
+		return cmd
+	}
+
+	merged := *cmd
+	merged.Flags = append([]GenericFlag{}, cmd.Flags...)
+	for _, f := range b.inheritedFlags {
+		merged.Flags = append(merged.Flags, f.GenericFlag)
+	}
+
+	return &merged
+}
+
+// inheritedFlagErrors finds flags of the command defined by raw that collide with the flags it inherits and constraints
+// referring to flags it neither defines nor inherits
+func (b *AppBuilder) inheritedFlagErrors(raw json.RawMessage) []string {
+	var cmd GenericCommand
+	if json.Unmarshal(raw, &cmd) != nil {
+		// commands that can not be decoded are reported elsewhere
+		return nil
+	}
+
+	var errs []string
+
+	if cmd.Constraints != nil {
+		errs = append(errs, cmd.Constraints.Validate(b.withInherited(&cmd).Flags)...)
+	}
+
+	for _, i := range b.inheritedFlags {
+		if i.Name == "prompt" && cmd.ConfirmPrompt != "" {
+			errs = append(errs, fmt.Sprintf("flag \"prompt\" added for confirm_prompt is already defined by %s", i.origin))
+		}
+
+		for _, f := range cmd.Flags {
+			switch {
+			case f.Name == i.Name:
+				errs = append(errs, fmt.Sprintf("flag %q is already defined by %s", f.Name, i.origin))
+			case f.Short != "" && f.Short == i.Short:
+				errs = append(errs, fmt.Sprintf("short flag %q of flag %q is already used by flag %q defined by %s", f.Short, f.Name, i.Name, i.origin))
+			}
+		}
+	}
+
+	return errs
+}
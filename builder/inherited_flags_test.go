@@ -0,0 +1,220 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/choria-io/fisk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// inheritingCommand is a command plugin whose flags are inherited by its sub commands when it has any, otherwise it
+// is created using CreateGenericCommand keeping the flags in inheritingFlags
+type inheritingCommand struct {
+	b   *AppBuilder
+	def testCommandDefinition
+}
+
+var inheritingFlags map[string]any
+
+func (c *inheritingCommand) CreateCommand(app KingpinCommand) (*fisk.CmdClause, error) {
+	if len(c.def.Commands) > 0 {
+		return app.Command(c.def.Name, c.def.Description), nil
+	}
+
+	inheritingFlags = map[string]any{}
+
+	return CreateGenericCommand(app, &c.def.GenericCommand, map[string]any{}, inheritingFlags, c.b, func(_ *fisk.ParseContext) error { return nil }), nil
+}
+
+func (c *inheritingCommand) SubCommands() []json.RawMessage { return c.def.Commands }
+func (c *inheritingCommand) Validate(log Logger) error      { return c.def.GenericCommand.Validate(log) }
+func (c *inheritingCommand) String() string                 { return fmt.Sprintf("%s (inheriting)", c.def.Name) }
+func (c *inheritingCommand) InheritedFlags() []GenericFlag  { return c.def.Flags }
+
+func registerInheritingCommand() {
+	RegisterCommand("inheriting", func(b *AppBuilder, j json.RawMessage, _ Logger) (Command, error) {
+		cmd := &inheritingCommand{b: b}
+		err := b.UnmarshalCommand(j, &cmd.def)
+		if err != nil {
+			return nil, err
+		}
+
+		return cmd, nil
+	}, nil)
+}
+
+var _ = Describe("Inherited flags", func() {
+	var b *AppBuilder
+
+	BeforeEach(func() {
+		registerInheritingCommand()
+	})
+
+	load := func(def string) (*fisk.Application, error) {
+		var err error
+		b, err = New(context.Background(), "test", WithLogger(NoopLogger{}), WithStdout(&bytes.Buffer{}), WithAppDefinitionBytes([]byte(def)))
+		Expect(err).ToNot(HaveOccurred())
+
+		return b.createAppCLI()
+	}
+
+	It("Should add the flags of the application and parents to all commands below them", func() {
+		def := `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+flags:
+  - name: verbose
+    description: verbose
+    bool: true
+commands:
+  - name: deploy
+    description: deploy
+    type: inheriting
+    flags:
+      - name: env
+        description: env
+        short: e
+        default: dev
+    commands:
+      - name: web
+        description: web
+        type: inheriting
+        flags:
+          - name: force
+            description: force
+            bool: true
+`
+		app, err := load(def)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.inheritedFlags).To(BeEmpty())
+
+		app.Terminate(func(int) {})
+		_, err = app.Parse([]string{"deploy", "web", "-e", "prod", "--verbose"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dereferenceArgsOrFlags(inheritingFlags)).To(Equal(map[string]any{"env": "prod", "verbose": true, "force": false}))
+
+		app, err = load(def)
+		Expect(err).ToNot(HaveOccurred())
+		app.Terminate(func(int) {})
+		_, err = app.Parse([]string{"deploy", "web"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dereferenceArgsOrFlags(inheritingFlags)).To(Equal(map[string]any{"env": "dev", "verbose": false, "force": false}))
+	})
+
+	It("Should detect flags colliding with inherited flags", func() {
+		def := `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+flags:
+  - name: verbose
+    description: verbose
+    short: v
+commands:
+  - name: deploy
+    description: deploy
+    type: inheriting
+    flags:
+      - name: verbose
+        description: verbose
+    commands:
+      - name: web
+        description: web
+        type: inheriting
+        confirm_prompt: Sure?
+        flags:
+          - name: version
+            description: version
+            short: v
+`
+		_, err := load(def)
+		Expect(err).To(MatchError(ErrInvalidDefinition))
+		Expect(err).To(MatchError(ContainSubstring(`root -> deploy (inheriting): flag "verbose" is already defined by the application`)))
+
+		v := newDefinitionValidator(b)
+		v.validateDefinition(b.def)
+		Expect(v.errs).To(ConsistOf(
+			`root -> deploy (inheriting): flag "verbose" is already defined by the application`,
+			`root -> deploy (inheriting) -> web (inheriting): short flag "v" of flag "version" is already used by flag "verbose" defined by the application`,
+		))
+
+		b.inheritedFlags = []inheritedFlag{{GenericFlag: GenericFlag{Name: "prompt"}, origin: "the application"}}
+		Expect(b.inheritedFlagErrors([]byte(`{"name":"x","confirm_prompt":"Sure?"}`))).To(Equal([]string{`flag "prompt" added for confirm_prompt is already defined by the application`}))
+	})
+
+	It("Should check constraints against own and inherited flags", func() {
+		def := `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+flags:
+  - name: token
+    description: token
+    env: GINKGO_TOKEN
+commands:
+  - name: deploy
+    description: deploy
+    type: inheriting
+    flags:
+      - name: env
+        description: env
+    commands:
+      - name: web
+        description: web
+        type: inheriting
+        flags:
+          - name: password
+            description: password
+        constraints:
+          exclusive:
+            - [token, password]
+          at_least_one:
+            - [env, %s]
+`
+		app, err := load(fmt.Sprintf(def, "password"))
+		Expect(err).ToNot(HaveOccurred())
+
+		v := newDefinitionValidator(b)
+		v.validateDefinition(b.def)
+		Expect(v.errs).To(BeEmpty())
+
+		app.Terminate(func(int) {})
+		_, err = app.Parse([]string{"deploy", "web", "--token", "x", "--password", "y"})
+		Expect(err).To(MatchError("only one of --token or --password can be given, got --token and --password"))
+
+		GinkgoT().Setenv("GINKGO_TOKEN", "x")
+		app, err = load(fmt.Sprintf(def, "password"))
+		Expect(err).ToNot(HaveOccurred())
+		app.Terminate(func(int) {})
+		_, err = app.Parse([]string{"deploy", "web", "--env", "prod"})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = load(fmt.Sprintf(def, "other"))
+		Expect(err).To(MatchError(ContainSubstring(`web (inheriting): at_least_one constraint refers to unknown flag "other"`)))
+	})
+
+	It("Should allow templates to reference inherited flags", func() {
+		b = &AppBuilder{}
+		cmd := &GenericCommand{Name: "x"}
+		templates := map[string]string{"command": `{{ .Flags.env }}`}
+
+		Expect(b.ValidateTemplates(cmd, nil, templates)).To(Equal([]string{`command references undeclared flag "env"`}))
+
+		err := b.withInheritedFlags("the application", []GenericFlag{{Name: "env"}}, func() error {
+			Expect(b.ValidateTemplates(cmd, nil, templates)).To(BeEmpty())
+			return nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.inheritedFlags).To(BeEmpty())
+	})
+
+})
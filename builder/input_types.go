@@ -0,0 +1,172 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+)
+
+// defaultDateLayouts are tried in order when parsing dates without a layout
+var defaultDateLayouts = []string{time.RFC3339, time.DateTime, time.DateOnly}
+
+// parsedInputTypes are types parsed by App Builder rather than fisk, templates receive the parsed value so its fields
+// and methods can be used, like .Seconds of a duration. layout is only used by date.
+var parsedInputTypes = map[string]func(value string, layout string) (any, error){
+	"duration": parseDurationInput,
+	"url":      parseURLInput,
+	"ip":       parseIPInput,
+	"cidr":     parseCIDRInput,
+	"regexp":   parseRegexpInput,
+	"json":     parseJSONInput,
+	"date":     parseDateInput,
+}
+
+func parseDurationInput(value string, _ string) (any, error) {
+	d, err := fisk.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration '%s'", value)
+	}
+
+	return d, nil
+}
+
+func parseURLInput(value string, _ string) (any, error) {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid url '%s'", value)
+	}
+
+	return u, nil
+}
+
+func parseIPInput(value string, _ string) (any, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid ip address '%s'", value)
+	}
+
+	return ip, nil
+}
+
+func parseCIDRInput(value string, _ string) (any, error) {
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cidr '%s'", value)
+	}
+
+	return network, nil
+}
+
+func parseRegexpInput(value string, _ string) (any, error) {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression '%s': %w", value, err)
+	}
+
+	return re, nil
+}
+
+func parseJSONInput(value string, _ string) (any, error) {
+	var res any
+	err := json.Unmarshal([]byte(value), &res)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	return res, nil
+}
+
+func parseDateInput(value string, layout string) (any, error) {
+	layouts := defaultDateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+
+	for _, l := range layouts {
+		t, err := time.ParseInLocation(l, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid date '%s', expected a date like %s", value, strings.Join(layouts, " or "))
+}
+
+// parsedValue is a fisk value storing values converted using parse, when values is set all values are kept rather than
+// value being set
+type parsedValue struct {
+	value  *any
+	values *[]any
+	parse  func(string) (any, error)
+	text   []string
+}
+
+func (v *parsedValue) String() string {
+	return strings.Join(v.text, ",")
+}
+
+func (v *parsedValue) Set(value string) error {
+	res, err := v.parse(value)
+	if err != nil {
+		return err
+	}
+
+	if v.values != nil {
+		*v.values = append(*v.values, res)
+		v.text = append(v.text, value)
+	} else {
+		*v.value = res
+		v.text = []string{value}
+	}
+
+	return nil
+}
+
+func (v *parsedValue) reset() {
+	if v.values != nil {
+		*v.values = []any{}
+	}
+	v.text = nil
+}
+
+// IsCumulative tells fisk the value can be set multiple times
+func (v *parsedValue) IsCumulative() bool {
+	return v.values != nil
+}
+
+// isParsedInputType reports whether dType, already normalized, is parsed by App Builder
+func isParsedInputType(dType string) bool {
+	_, ok := parsedInputTypes[dType]
+	return ok
+}
+
+// applyParsedInputType configures c to parse values of dType and returns the fisk value pointer
+func applyParsedInputType(c parserClause, dType string, layout string, multiple bool) any {
+	parse := parsedInputTypes[dType]
+	value := &parsedValue{
+		parse: func(s string) (any, error) {
+			return parse(s, layout)
+		},
+	}
+
+	c.SetValue(value)
+
+	if multiple {
+		value.values = &[]any{}
+		return value.values
+	}
+
+	value.value = new(any)
+
+	return value.value
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LintSettings configures the checks done by appbuilder lint
+type LintSettings struct {
+	// Disable is a list of lint rules to skip
+	Disable []string `json:"disable"`
+}
+
+const (
+	// lintDuplicateNames finds commands with the same name or alias as a sibling
+	lintDuplicateNames = "duplicate_names"
+	// lintPromptFlag finds flags named prompt on commands with a confirm_prompt, shadowing the generated flag
+	lintPromptFlag = "prompt_flag"
+	// lintUndeclaredReferences finds templates referencing flags or arguments the command does not declare
+	lintUndeclaredReferences = "undeclared_references"
+	// lintUnusedSecrets finds secrets that are not referenced by any template in the command
+	lintUnusedSecrets = "unused_secrets"
+	// lintScriptErrexit finds shell scripts that do not exit on errors using set -e
+	lintScriptErrexit = "script_errexit"
+	// lintShortFlags finds flags in a command sharing the same short flag
+	lintShortFlags = "short_flags"
+)
+
+var (
+	lintRules = []string{
+		lintDuplicateNames,
+		lintPromptFlag,
+		lintScriptErrexit,
+		lintShortFlags,
+		lintUndeclaredReferences,
+		lintUnusedSecrets,
+	}
+
+	lintErrexitPattern = regexp.MustCompile(`(?m)^\s*set\s+(-[a-zA-Z]*e|.*-o\s+errexit)`)
+	lintShells         = []string{"sh", "bash", "zsh", "ksh", "dash"}
+)
+
+// lintCommand is the part of any command definition that lint inspects
+type lintCommand struct {
+	GenericCommand
+
+	Script      string `json:"script"`
+	Interpreter string `json:"interpreter"`
+	Shebang     bool   `json:"shebang"`
+}
+
+// definitionLinter finds problems in definitions that are valid but likely mistakes
+type definitionLinter struct {
+	v        *definitionValidator
+	disabled map[string]bool
+}
+
+func newDefinitionLinter(b *AppBuilder, d *Definition) *definitionLinter {
+	l := &definitionLinter{
+		v:        newDefinitionValidator(b),
+		disabled: map[string]bool{},
+	}
+
+	if d.Lint != nil {
+		for _, rule := range d.Lint.Disable {
+			l.disabled[rule] = true
+		}
+	}
+
+	return l
+}
+
+// problems are all problems found so far
+func (l *definitionLinter) problems() []string {
+	return l.v.errs
+}
+
+// report records a problem found by rule in property, empty for the command itself, of the command at path
+func (l *definitionLinter) report(rule string, path string, property string, bread []string, format string, a ...any) {
+	if l.disabled[rule] {
+		return
+	}
+
+	msg := fmt.Sprintf(format, a...)
+	if len(bread) > 0 {
+		msg = fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), msg)
+	}
+
+	l.v.addCommandError(path, property, fmt.Sprintf("%s (%s)", msg, rule))
+}
+
+func (l *definitionLinter) lintDefinition(d *Definition) {
+	l.v.d = d
+	src := l.v.source(l.v.b.definitionPath)
+
+	if d.Lint != nil {
+		for i, rule := range d.Lint.Disable {
+			if !slices.Contains(lintRules, rule) {
+				l.v.addError(src, fmt.Sprintf("$.lint.disable[%d]", i), fmt.Sprintf("unknown lint rule %q, valid rules are %s", rule, strings.Join(lintRules, ", ")))
+			}
+		}
+	}
+
+	l.v.b.withInheritedFlags("the application", d.Flags, func() error {
+		l.lintCommands([]string{"root"}, "$.commands", d.Commands, d.commands)
+		return nil
+	})
+}
+
+// lintCommands lints sibling commands defined by raw and created as cmds found at path
+func (l *definitionLinter) lintCommands(bread []string, path string, raw []json.RawMessage, cmds []Command) {
+	seen := map[string]string{}
+
+	for i, j := range raw {
+		cmdPath := fmt.Sprintf("%s[%d]", path, i)
+
+		var c lintCommand
+		err := json.Unmarshal(j, &c)
+		if err != nil {
+			// validate reports commands that can not be decoded
+			continue
+		}
+
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			if other, ok := seen[name]; ok {
+				l.report(lintDuplicateNames, cmdPath, "", bread, "%q is used by both %s and %s", name, other, c.Name)
+				continue
+			}
+			seen[name] = c.Name
+		}
+
+		// commands that could not be created are reported by validate
+		if i >= len(cmds) || cmds[i] == nil {
+			continue
+		}
+
+		l.lintCommand(append(append([]string{}, bread...), cmds[i].String()), cmdPath, j, &c, cmds[i])
+	}
+}
+
+func (l *definitionLinter) lintCommand(bread []string, path string, raw json.RawMessage, c *lintCommand, cmd Command) {
+	shorts := map[string]string{}
+	for i, f := range c.Flags {
+		if f.Name == "prompt" && c.ConfirmPrompt != "" {
+			l.report(lintPromptFlag, path, fmt.Sprintf("flags[%d]", i), bread, "flag prompt shadows the flag added for confirm_prompt")
+		}
+
+		if f.Short == "" {
+			continue
+		}
+
+		if other, ok := shorts[f.Short]; ok {
+			l.report(lintShortFlags, path, fmt.Sprintf("flags[%d]", i), bread, "short flag %q is used by both %s and %s", f.Short, other, f.Name)
+			continue
+		}
+		shorts[f.Short] = f.Name
+	}
+
+	refs := definitionReferences(raw)
+
+	for _, ref := range refs {
+		switch {
+		case ref.kind == "Flags" && !c.hasFlag(ref.name) && !l.v.b.inheritsFlag(ref.name):
+			l.report(lintUndeclaredReferences, path, "", bread, "template references undeclared flag %q", ref.name)
+		case ref.kind == "Arguments" && !c.hasArgument(ref.name):
+			l.report(lintUndeclaredReferences, path, "", bread, "template references undeclared argument %q", ref.name)
+		}
+	}
+
+	for i, s := range c.Secrets {
+		if !slices.Contains(refs, templateReference{kind: "Secrets", name: s.Name}) {
+			l.report(lintUnusedSecrets, path, fmt.Sprintf("secrets[%d]", i), bread, "secret %q is not used", s.Name)
+		}
+	}
+
+	if c.Script != "" && c.isShellScript() && !lintErrexitPattern.MatchString(c.Script) {
+		l.report(lintScriptErrexit, path, "script", bread, "script does not use set -e")
+	}
+
+	var subs []Command
+	for _, sub := range cmd.SubCommands() {
+		// nil for commands that could not be created, keeping the list in line with the definitions
+		sc, _ := l.v.b.createCommand(sub)
+		subs = append(subs, sc)
+	}
+
+	l.v.b.withCommandFlags(cmd, func() error {
+		l.lintCommands(bread, path+".commands", cmd.SubCommands(), subs)
+		return nil
+	})
+}
+
+// isShellScript determines if the script is run by a shell, either the default one or one set using interpreter or a
+// #! line used with shebang
+func (c *lintCommand) isShellScript() bool {
+	interpreter := c.Interpreter
+	if interpreter == "" && c.Shebang && strings.HasPrefix(c.Script, "#!") {
+		interpreter, _, _ = strings.Cut(strings.TrimPrefix(c.Script, "#!"), "\n")
+	}
+
+	if interpreter == "" {
+		return true
+	}
+
+	parts := strings.Fields(interpreter)
+	if len(parts) == 0 {
+		return true
+	}
+
+	// #!/usr/bin/env bash
+	name := filepath.Base(parts[0])
+	if name == "env" && len(parts) > 1 {
+		name = filepath.Base(parts[1])
+	}
+
+	return slices.Contains(lintShells, name)
+}
+
+// definitionReferences finds all .Flags, .Arguments and .Secrets references in templates found in any string of the
+// command definition excluding its sub commands
+func definitionReferences(raw json.RawMessage) []templateReference {
+	var def map[string]any
+	err := json.Unmarshal(raw, &def)
+	if err != nil {
+		return nil
+	}
+	delete(def, "commands")
+
+	var refs []templateReference
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch val := v.(type) {
+		case string:
+			if !strings.Contains(val, "{{") {
+				return
+			}
+
+			// invalid templates are reported by validate
+			found, _ := templateReferences(val, nil)
+			for _, ref := range found {
+				if !slices.Contains(refs, ref) {
+					refs = append(refs, ref)
+				}
+			}
+		case []any:
+			for _, i := range val {
+				walk(i)
+			}
+		case map[string]any:
+			for _, i := range val {
+				walk(i)
+			}
+		}
+	}
+	walk(def)
+
+	sortTemplateReferences(refs)
+
+	return refs
+}
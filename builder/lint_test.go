@@ -0,0 +1,112 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lint", func() {
+	var def string
+
+	lint := func(body string) []string {
+		def = writeTestFile(GinkgoT().TempDir(), "test-app.yaml", body)
+
+		b, d, err := loadTestDefinition(def, withDeferredUnknownKeys())
+		Expect(err).ToNot(HaveOccurred())
+
+		l := newDefinitionLinter(b, d)
+		l.lintDefinition(d)
+
+		return l.problems()
+	}
+
+	BeforeEach(func() {
+		registerTestCommand()
+	})
+
+	It("Should find likely mistakes", func() {
+		problems := lint(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+
+commands:
+  - name: one
+    description: one
+    type: test
+    aliases: [two]
+    confirm_prompt: Really?
+    script: echo {{ .Flags.missing }} {{ .Arguments.arg }} {{ index .Flags "prompt" }} {{ .Secrets.used }}
+    flags:
+      - name: prompt
+        description: prompt
+        short: p
+      - name: other
+        description: other
+        short: p
+    arguments:
+      - name: arg
+        description: arg
+    secrets:
+      - name: used
+        one_password: {reference: op://x/y/z}
+      - name: unused
+        one_password: {reference: op://x/y/z}
+    commands:
+      - name: python
+        description: python
+        type: test
+        shebang: true
+        script: |
+          #!/usr/bin/env python3
+          print("{{ .Secrets.used }}")
+  - name: two
+    description: two
+    type: test
+    script: |
+      set -euo pipefail
+      echo {{ .Secrets.used }}
+`)
+
+		Expect(problems).To(Equal([]string{
+			fmt.Sprintf("%s:14:9: root -> one (test): flag prompt shadows the flag added for confirm_prompt (prompt_flag)", def),
+			fmt.Sprintf("%s:17:9: root -> one (test): short flag \"p\" is used by both prompt and other (short_flags)", def),
+			fmt.Sprintf("%s:7:5: root -> one (test): template references undeclared flag \"missing\" (undeclared_references)", def),
+			fmt.Sprintf("%s:26:9: root -> one (test): secret \"unused\" is not used (unused_secrets)", def),
+			fmt.Sprintf("%s:12:13: root -> one (test): script does not use set -e (script_errexit)", def),
+			fmt.Sprintf("%s:36:5: root: \"two\" is used by both one and two (duplicate_names)", def),
+		}))
+	})
+
+	It("Should support disabling rules", func() {
+		problems := lint(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+lint:
+  disable:
+    - script_errexit
+    - duplicate_names
+    - unknown
+
+commands:
+  - name: one
+    description: one
+    type: test
+    script: echo hello
+  - name: one
+    description: one
+    type: test
+`)
+
+		Expect(problems).To(Equal([]string{
+			fmt.Sprintf(`%s:9:7: unknown lint rule "unknown", valid rules are duplicate_names, prompt_flag, script_errexit, short_flags, undeclared_references, unused_secrets`, def),
+		}))
+	})
+})
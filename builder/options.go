@@ -6,6 +6,7 @@ package builder
 
 import (
 	"io"
+	"time"
 )
 
 // Option configures the builder
@@ -44,6 +45,14 @@ func WithAppDefinitionFile(f string) Option {
 	}
 }
 
+// WithEnvironment selects the environment specific overlay to apply to definitions, defaults to BUILDER_ENV
+func WithEnvironment(env string) Option {
+	return func(b *AppBuilder) error {
+		b.env = env
+		return nil
+	}
+}
+
 // WithContextualUsageOnError handles application termination by showing contextual help rather than returning an error
 func WithContextualUsageOnError() Option {
 	return func(b *AppBuilder) error {
@@ -67,3 +76,11 @@ func WithStderr(w io.Writer) Option {
 		return nil
 	}
 }
+
+// WithInterruptGrace sets how long to wait for commands to shut down after an interrupt before the application exits
+func WithInterruptGrace(d time.Duration) Option {
+	return func(b *AppBuilder) error {
+		b.interruptGrace = d
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// overlayFiles finds the overlays for the definition in path, for myapp-app.yaml these are myapp-app.d/*.yaml in
+// alphabetical order followed by myapp-app.<env>.yaml when env is set
+func overlayFiles(path string, env string) ([]string, error) {
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+
+	files, err := filepath.Glob(filepath.Join(stem+".d", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	if env != "" {
+		envFile := fmt.Sprintf("%s.%s%s", stem, env, filepath.Ext(path))
+		if fileExist(envFile) {
+			files = append(files, envFile)
+		}
+	}
+
+	return files, nil
+}
+
+// applyOverlay merges the overlay in file onto d and its expanded commands cmds. Settings in the overlay replace
+// those in d, commands are matched by name and merged while new commands are added.
+func (e *includeExpander) applyOverlay(d *Definition, cmds []any, file string) ([]any, error) {
+	_, j, err := e.load(Include{File: file}, "")
+	if err != nil {
+		return nil, err
+	}
+	defer e.pop()
+
+	var overlay map[string]any
+	err = json.Unmarshal(j, &overlay)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, file, err)
+	}
+
+	if _, ok := overlay["include_file"]; ok {
+		return nil, fmt.Errorf("%w: %s: overlays can not include other files", ErrInvalidDefinition, file)
+	}
+
+	unknown, err := findUnknownKeys(j, reflect.TypeOf(d))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, file, err)
+	}
+	for _, k := range unknown {
+		k.file = file
+		e.unknown = append(e.unknown, k)
+	}
+
+	ocmds, _ := overlay["commands"].([]any)
+	for i, cmd := range ocmds {
+		m, ok := cmd.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		path := fmt.Sprintf("$.commands[%d]", i)
+		m[originKey] = commandOrigin{file: file, path: path}
+
+		err = e.expandCommand(m, file, path, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	delete(overlay, "commands")
+
+	// decoding onto the existing definition replaces only the settings found in the overlay
+	settings, err := json.Marshal(overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(settings, d)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDefinition, file, err)
+	}
+
+	return mergeCommands(cmds, ocmds), nil
+}
+
+// mergeCommands merges overlay commands onto those in base with the same name, others are added
+func mergeCommands(base []any, overlay []any) []any {
+	for _, cmd := range overlay {
+		m, ok := cmd.(map[string]any)
+		if !ok {
+			base = append(base, cmd)
+			continue
+		}
+
+		target := findCommand(base, m["name"])
+		if target == nil {
+			base = append(base, m)
+			continue
+		}
+
+		mergeSettings(target, m)
+	}
+
+	return base
+}
+
+func findCommand(cmds []any, name any) map[string]any {
+	if name == nil {
+		return nil
+	}
+
+	for _, cmd := range cmds {
+		m, ok := cmd.(map[string]any)
+		if ok && m["name"] == name {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// mergeSettings merges the command settings in src into dst, maps are merged, lists other than commands are replaced
+// and null removes a setting
+func mergeSettings(dst map[string]any, src map[string]any) {
+	for k, v := range src {
+		if k == originKey {
+			continue
+		}
+
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+
+		switch val := v.(type) {
+		case map[string]any:
+			if cur, ok := dst[k].(map[string]any); ok {
+				mergeSettings(cur, val)
+				continue
+			}
+
+		case []any:
+			if cur, ok := dst[k].([]any); ok && k == "commands" {
+				dst[k] = mergeCommands(cur, val)
+				continue
+			}
+		}
+
+		dst[k] = v
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+)
+
+var _ = Describe("Overlays", func() {
+	var (
+		td   string
+		load func(string) (*Definition, error)
+	)
+
+	BeforeEach(func() {
+		registerTestCommand()
+		td = GinkgoT().TempDir()
+
+		load = func(env string) (*Definition, error) {
+			_, d, err := loadTestDefinition(filepath.Join(td, "test-app.yaml"), WithEnvironment(env))
+			return d, err
+		}
+
+		writeTestFile(td, "test-app.yaml", `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+commands:
+  - name: parent
+    description: parent
+    type: test
+    aliases: [p]
+    commands:
+      - name: child
+        description: child
+        type: test
+        flags:
+          - name: verbose
+            description: verbose
+  - name: other
+    description: other
+    type: test
+    aliases: [o]
+`)
+	})
+
+	Describe("overlayFiles", func() {
+		It("Should find overlays in order", func() {
+			writeTestFile(td, "test-app.d/b.yaml", "")
+			writeTestFile(td, "test-app.d/a.yaml", "")
+			writeTestFile(td, "test-app.d/ignored.txt", "")
+			writeTestFile(td, "test-app.prod.yaml", "")
+
+			files, err := overlayFiles(filepath.Join(td, "test-app.yaml"), "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(Equal([]string{filepath.Join(td, "test-app.d/a.yaml"), filepath.Join(td, "test-app.d/b.yaml")}))
+
+			files, err = overlayFiles(filepath.Join(td, "test-app.yaml"), "prod")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(3))
+			Expect(files[2]).To(Equal(filepath.Join(td, "test-app.prod.yaml")))
+
+			files, err = overlayFiles(filepath.Join(td, "test-app.yaml"), "dev")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(2))
+		})
+	})
+
+	It("Should merge overlays onto the definition", func() {
+		writeTestFile(td, "test-app.d/10-common.yaml", `description: overlaid
+commands:
+  - name: parent
+    description: overlaid parent
+    commands:
+      - name: child
+        flags:
+          - name: debug
+            description: debug
+      - name: added
+        description: added
+        type: test
+  - name: other
+    aliases: null
+`)
+		writeTestFile(td, "test-app.prod.yaml", `version: 2.0.0
+commands:
+  - name: production
+    description: production
+    type: test
+`)
+
+		d, err := load("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Description).To(Equal("overlaid"))
+		Expect(d.Version).To(Equal("1.0.0"))
+		Expect(d.overlays).To(Equal([]string{filepath.Join(td, "test-app.d/10-common.yaml")}))
+		Expect(d.Commands).To(HaveLen(2))
+
+		parent := d.Commands[0]
+		Expect(gjson.GetBytes(parent, "description").String()).To(Equal("overlaid parent"))
+		Expect(gjson.GetBytes(parent, "type").String()).To(Equal("test"))
+		Expect(gjson.GetBytes(parent, "aliases").String()).To(Equal(`["p"]`))
+		Expect(gjson.GetBytes(parent, "commands.#.name").String()).To(Equal(`["child","added"]`))
+		Expect(gjson.GetBytes(parent, "commands.0.description").String()).To(Equal("child"))
+		Expect(gjson.GetBytes(parent, "commands.0.flags.#.name").String()).To(Equal(`["debug"]`))
+		Expect(gjson.GetBytes(d.Commands[1], "aliases").Exists()).To(BeFalse())
+
+		Expect(d.origins["$.commands[0].commands[1]"].file).To(Equal(filepath.Join(td, "test-app.d/10-common.yaml")))
+		Expect(d.origins["$.commands[0].commands[0]"].file).To(Equal(filepath.Join(td, "test-app.yaml")))
+
+		d, err = load("prod")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Version).To(Equal("2.0.0"))
+		Expect(d.overlays).To(HaveLen(2))
+		Expect(gjson.GetBytes(d.Commands[2], "name").String()).To(Equal("production"))
+	})
+
+	It("Should not allow overlays to include files", func() {
+		writeTestFile(td, "test-app.prod.yaml", `include_file: other.yaml`)
+
+		_, err := load("prod")
+		Expect(err).To(MatchError(ContainSubstring("overlays can not include other files")))
+	})
+
+	It("Should reject unknown keys in overlays", func() {
+		writeTestFile(td, "test-app.prod.yaml", `descripton: x`)
+
+		_, err := load("prod")
+		Expect(err).To(MatchError(`invalid definition: unknown key "descripton", did you mean "description"`))
+	})
+})
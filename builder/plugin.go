@@ -22,8 +22,9 @@ var (
 // CommandConstructor should exist in any package that is used as a plugin
 type CommandConstructor func(*AppBuilder, json.RawMessage, Logger) (Command, error)
 
-// RegisterCommand adds a new kind of command
-func RegisterCommand(kind string, constructor CommandConstructor) error {
+// RegisterCommand adds a new kind of command, definition is usually the struct the command is decoded into and is used
+// to generate the JSON Schema describing application definitions and to find unknown keys, nil when not known
+func RegisterCommand(kind string, constructor CommandConstructor, definition any) error {
 	pmu.Lock()
 	defer pmu.Unlock()
 
@@ -37,13 +38,16 @@ func RegisterCommand(kind string, constructor CommandConstructor) error {
 	}
 
 	commandPlugins[kind] = &plugin{constructor}
+	if definition != nil {
+		commandSchemas[kind] = definition
+	}
 
 	return nil
 }
 
 // MustRegisterCommand registers a command and panics if it cannot
-func MustRegisterCommand(kind string, constructor CommandConstructor) {
-	err := RegisterCommand(kind, constructor)
+func MustRegisterCommand(kind string, constructor CommandConstructor, definition any) {
+	err := RegisterCommand(kind, constructor, definition)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,181 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/choria-io/fisk"
+)
+
+var (
+	// surveyAskOne asks questions when prompting for missing values, replaced in tests
+	surveyAskOne = survey.AskOne
+
+	// stdinIsTerminal determines if we can prompt for missing values, replaced in tests
+	stdinIsTerminal = func() bool {
+		stat, err := os.Stdin.Stat()
+		return err == nil && stat.Mode()&os.ModeCharDevice != 0
+	}
+)
+
+// promptInput is a required flag or argument that is asked for interactively when not given
+type promptInput struct {
+	name        string
+	description string
+	// clause is the *fisk.ArgClause or *fisk.FlagClause used to determine if the input was given
+	clause   any
+	value    fisk.Value
+	envVar   string
+	config   string
+	dType    string
+	enum     []string
+	enumFrom *EnumSource
+	multiple bool
+	// check is the validation applied by fisk to values given on the command line
+	check fisk.OptionValidator
+}
+
+// shouldPrompt determines if a required input without a default should be prompted for rather than be required by fisk
+func (b *AppBuilder) shouldPrompt(prompt bool, required bool, dflt any) bool {
+	if !required || dflt != nil {
+		return false
+	}
+
+	if !prompt && (b == nil || b.def == nil || !b.def.PromptMissing) {
+		return false
+	}
+
+	return stdinIsTerminal()
+}
+
+// promptMissing asks for the values of all inputs not given on the command line or in the environment
+func (b *AppBuilder) promptMissing(pc *fisk.ParseContext, inputs []*promptInput) error {
+	for _, input := range inputs {
+		if input.given(pc, b.cfg) {
+			continue
+		}
+
+		err := input.ask(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// given determines if the value was given on the command line, in the environment or in the configuration
+func (p *promptInput) given(pc *fisk.ParseContext, cfg map[string]any) bool {
+	if pc != nil {
+		for _, el := range pc.Elements {
+			if el.Clause == p.clause {
+				return true
+			}
+		}
+	}
+
+	if p.config != "" {
+		if _, ok := configValue(cfg, p.config); ok {
+			return true
+		}
+	}
+
+	return p.envVar != "" && os.Getenv(p.envVar) != ""
+}
+
+// ask prompts for the value, enums are shown as select lists and booleans as confirmations
+func (p *promptInput) ask(b *AppBuilder) error {
+	options := p.enum
+	if p.enumFrom != nil {
+		var err error
+		options, err = b.enumValues(p.enumFrom)
+		if err != nil {
+			return fmt.Errorf("%s: could not determine valid values: %w", p.name, err)
+		}
+	}
+
+	var (
+		answers []string
+		err     error
+	)
+
+	switch {
+	case p.dType == "bool":
+		var ans bool
+		err = surveyAskOne(&survey.Confirm{Message: p.name, Help: p.description}, &ans)
+		answers = []string{strconv.FormatBool(ans)}
+
+	case len(options) > 0 && p.multiple:
+		err = surveyAskOne(&survey.MultiSelect{Message: p.name, Help: p.description, Options: options}, &answers, survey.WithValidator(survey.Required))
+
+	case len(options) > 0:
+		var ans string
+		err = surveyAskOne(&survey.Select{Message: p.name, Help: p.description, Options: options}, &ans)
+		answers = []string{ans}
+
+	default:
+		var ans string
+		err = surveyAskOne(&survey.Input{Message: p.name, Help: p.description}, &ans, survey.WithValidator(p.validate))
+		answers = p.split(ans)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, ans := range answers {
+		err = p.value.Set(ans)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.name, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks the answer using the validation applied to values given on the command line
+func (p *promptInput) validate(ans any) error {
+	entries := p.split(fmt.Sprintf("%v", ans))
+	if len(entries) == 0 {
+		return errors.New("a value is required")
+	}
+
+	if p.check == nil {
+		return nil
+	}
+
+	for _, e := range entries {
+		err := p.check(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// split splits answers for inputs accepting many values on commas
+func (p *promptInput) split(ans string) []string {
+	if !p.multiple && p.dType != "map" {
+		if strings.TrimSpace(ans) == "" {
+			return nil
+		}
+		return []string{ans}
+	}
+
+	var res []string
+	for _, v := range strings.Split(ans, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/choria-io/fisk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prompting for missing values", func() {
+	var (
+		b        *AppBuilder
+		answers  map[string]any
+		asked    []string
+		terminal bool
+		cb       = func(_ *fisk.ParseContext) error { return nil }
+	)
+
+	BeforeEach(func() {
+		b = &AppBuilder{ctx: context.Background(), log: NoopLogger{}, cfg: map[string]any{}, def: &Definition{}}
+		answers = map[string]any{}
+		asked = nil
+		terminal = true
+
+		origAsk, origTerminal := surveyAskOne, stdinIsTerminal
+		DeferCleanup(func() {
+			surveyAskOne, stdinIsTerminal = origAsk, origTerminal
+		})
+
+		stdinIsTerminal = func() bool { return terminal }
+		surveyAskOne = func(p survey.Prompt, response any, _ ...survey.AskOpt) error {
+			var msg string
+			switch q := p.(type) {
+			case *survey.Input:
+				msg = q.Message
+			case *survey.Select:
+				msg = q.Message
+			case *survey.MultiSelect:
+				msg = q.Message
+			case *survey.Confirm:
+				msg = q.Message
+			}
+
+			asked = append(asked, fmt.Sprintf("%s %T", msg, p))
+			reflect.ValueOf(response).Elem().Set(reflect.ValueOf(answers[msg]))
+
+			return nil
+		}
+	})
+
+	parse := func(d *GenericCommand, args ...string) (map[string]any, map[string]any, error) {
+		arguments := map[string]any{}
+		flags := map[string]any{}
+		app := fisk.New("app", "app")
+		app.Terminate(func(int) {})
+		CreateGenericCommand(app, d, arguments, flags, b, cb)
+
+		_, err := app.Parse(append([]string{"ginkgo"}, args...))
+
+		return dereferenceArgsOrFlags(arguments), dereferenceArgsOrFlags(flags), err
+	}
+
+	It("Should prompt for missing required values", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Arguments = []GenericArgument{{Name: "count", Description: "help", Type: "int", Required: true, Prompt: true}}
+		d.Flags = []GenericFlag{
+			{Name: "env", Description: "help", Enum: []string{"dev", "prod"}, Required: true, Prompt: true},
+			{Name: "zones", Description: "help", EnumFrom: &EnumSource{Command: "echo a; echo b"}, Multiple: true, Required: true, Prompt: true},
+			{Name: "force", Description: "help", Bool: true, Required: true, Prompt: true},
+			{Name: "tags", Description: "help", Multiple: true, Required: true, Prompt: true},
+		}
+
+		answers = map[string]any{"count": "10", "env": "prod", "zones": []string{"a", "b"}, "force": true, "tags": "x, y"}
+
+		args, flags, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(asked).To(Equal([]string{"count *survey.Input", "env *survey.Select", "zones *survey.MultiSelect", "force *survey.Confirm", "tags *survey.Input"}))
+		Expect(args["count"]).To(Equal(int64(10)))
+		Expect(flags["env"]).To(Equal("prod"))
+		Expect(flags["zones"]).To(Equal([]string{"a", "b"}))
+		Expect(flags["force"]).To(BeTrue())
+		Expect(flags["tags"]).To(Equal([]string{"x", "y"}))
+	})
+
+	It("Should not prompt for values that were given", func() {
+		GinkgoT().Setenv("GINKGO_ENV", "dev")
+
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Arguments = []GenericArgument{{Name: "name", Description: "help", Required: true, Prompt: true}}
+		d.Flags = []GenericFlag{{Name: "env", Description: "help", EnvVar: "GINKGO_ENV", Required: true, Prompt: true}}
+
+		args, flags, err := parse(d, "x")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(asked).To(BeEmpty())
+		Expect(args["name"]).To(Equal("x"))
+		Expect(flags["env"]).To(Equal("dev"))
+	})
+
+	It("Should prompt for required arguments following prompted arguments", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Arguments = []GenericArgument{
+			{Name: "first", Description: "help", Required: true, Prompt: true},
+			{Name: "second", Description: "help", Required: true},
+		}
+		answers = map[string]any{"first": "1", "second": "2"}
+
+		args, _, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(asked).To(Equal([]string{"first *survey.Input", "second *survey.Input"}))
+		Expect(args).To(Equal(map[string]any{"first": "1", "second": "2"}))
+
+		asked = nil
+		args, _, err = parse(d, "x")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(asked).To(Equal([]string{"second *survey.Input"}))
+		Expect(args).To(Equal(map[string]any{"first": "x", "second": "2"}))
+	})
+
+	It("Should prompt for all required values when enabled for the application", func() {
+		b.def.PromptMissing = true
+
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Arguments = []GenericArgument{
+			{Name: "name", Description: "help", Required: true},
+			{Name: "other", Description: "help", Required: true, Default: "x"},
+		}
+		answers = map[string]any{"name": "n"}
+
+		args, _, err := parse(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(asked).To(Equal([]string{"name *survey.Input"}))
+		Expect(args["name"]).To(Equal("n"))
+		Expect(args["other"]).To(Equal("x"))
+	})
+
+	It("Should not prompt without a terminal", func() {
+		terminal = false
+
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Arguments = []GenericArgument{{Name: "name", Description: "help", Required: true, Prompt: true}}
+
+		_, _, err := parse(d)
+		Expect(err).To(MatchError(ContainSubstring("required argument 'name' not provided")))
+		Expect(asked).To(BeEmpty())
+	})
+
+	It("Should fail for invalid answers", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Arguments = []GenericArgument{{Name: "count", Description: "help", Type: "int", Required: true, Prompt: true}}
+		answers = map[string]any{"count": "many"}
+
+		_, _, err := parse(d)
+		Expect(err).To(MatchError(ContainSubstring(`count: strconv.ParseFloat: parsing "many"`)))
+	})
+
+	It("Should validate answers", func() {
+		p := &promptInput{name: "x", check: mapEntryValidator(`key != "bad"`), dType: "map"}
+		Expect(p.validate("a=1, b=2")).To(Succeed())
+		Expect(p.validate(" ")).To(MatchError("a value is required"))
+		Expect(p.validate("a=1,bad=2")).To(MatchError(ContainSubstring(`validation of "bad"`)))
+	})
+
+	It("Should only allow prompts for required values", func() {
+		d := &GenericCommand{Name: "ginkgo", Type: "exec", Description: "help"}
+		d.Arguments = []GenericArgument{{Name: "a", Description: "help", Prompt: true}}
+		d.Flags = []GenericFlag{{Name: "f", Description: "help", Required: true, Default: "x", Prompt: true}}
+
+		err := d.Validate(nil)
+		Expect(err).To(MatchError(ContainSubstring(`argument "a" can only prompt when required and without a default`)))
+		Expect(err).To(MatchError(ContainSubstring(`flag "f" can only prompt when required and without a default`)))
+	})
+})
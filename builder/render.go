@@ -0,0 +1,147 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// renderKeyOrder are keys shown first when rendering definitions, in this order, other keys are sorted and commands
+// are shown last
+var renderKeyOrder = []string{"name", "description", "version", "author", "type", "aliases"}
+
+// renderDefinition renders d, with all includes, overlays and templates expanded, as YAML or JSON. When path is given
+// only the command found by following the command names in path is rendered.
+func renderDefinition(d *Definition, path []string, format string) ([]byte, error) {
+	j, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var def map[string]any
+	err = json.Unmarshal(j, &def)
+	if err != nil {
+		return nil, err
+	}
+
+	// already expanded into the commands
+	delete(def, "include_file")
+	delete(def, "templates")
+
+	var res any = def
+	for i := range path {
+		cmds, _ := res.(map[string]any)["commands"].([]any)
+		cmd := findCommand(cmds, path[i])
+		if cmd == nil {
+			return nil, fmt.Errorf("command %q not found", strings.Join(path[:i+1], " "))
+		}
+		res = cmd
+	}
+
+	res = pruneEmpty(res)
+
+	switch format {
+	case "json":
+		out := &bytes.Buffer{}
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(res)
+
+		return out.Bytes(), err
+	case "yaml":
+		return yaml.MarshalWithOptions(orderKeys(res), yaml.UseLiteralStyleIfMultiline(true))
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// pruneEmpty removes null and empty lists and maps from v, false and empty strings are kept as they can be set on
+// purpose, for example to override an included value
+func pruneEmpty(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		res := map[string]any{}
+		for k, item := range val {
+			item = pruneEmpty(item)
+			if !isEmptyValue(item) {
+				res[k] = item
+			}
+		}
+		return res
+
+	case []any:
+		res := []any{}
+		for _, item := range val {
+			res = append(res, pruneEmpty(item))
+		}
+		return res
+
+	default:
+		return v
+	}
+}
+
+func isEmptyValue(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case []any:
+		return len(val) == 0
+	case map[string]any:
+		return len(val) == 0
+	default:
+		return false
+	}
+}
+
+// orderKeys turns maps in v into ordered maps showing the most important keys first
+func orderKeys(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		var keys []string
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return renderKeyRank(keys[i]) < renderKeyRank(keys[j]) || (renderKeyRank(keys[i]) == renderKeyRank(keys[j]) && keys[i] < keys[j])
+		})
+
+		res := yaml.MapSlice{}
+		for _, k := range keys {
+			res = append(res, yaml.MapItem{Key: k, Value: orderKeys(val[k])})
+		}
+		return res
+
+	case []any:
+		res := []any{}
+		for _, item := range val {
+			res = append(res, orderKeys(item))
+		}
+		return res
+
+	default:
+		return v
+	}
+}
+
+func renderKeyRank(key string) int {
+	if key == "commands" {
+		return len(renderKeyOrder) + 1
+	}
+
+	if i := slices.Index(renderKeyOrder, key); i != -1 {
+		return i
+	}
+
+	return len(renderKeyOrder)
+}
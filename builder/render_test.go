@@ -0,0 +1,101 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Render", func() {
+	var d *Definition
+
+	BeforeEach(func() {
+		registerTestCommand()
+		td := GinkgoT().TempDir()
+
+		def := writeTestFile(td, "test-app.yaml", `name: test
+description: test
+version: 1.0.0
+author: ginkgo
+include_file: sub.yaml
+templates:
+  child:
+    parameters:
+      - name: who
+    command:
+      type: test
+      description: Greets ((who))
+commands:
+  - type: test
+    name: parent
+    description: parent
+    commands:
+      - name: child
+        use: child
+        with:
+          who: world
+        banner: |
+          line 1
+          line 2 < 3
+`)
+		writeTestFile(td, "sub.yaml", `commands:
+  - name: included
+    description: included
+    type: test
+`)
+
+		var err error
+		_, d, err = loadTestDefinition(def)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should render the expanded definition as YAML", func() {
+		out, err := renderDefinition(d, nil, "yaml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(out)).To(Equal(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+commands:
+- name: parent
+  description: parent
+  type: test
+  commands:
+  - name: child
+    description: Greets world
+    type: test
+    banner: |
+      line 1
+      line 2 < 3
+- name: included
+  description: included
+  type: test
+`))
+	})
+
+	It("Should render a command as JSON", func() {
+		out, err := renderDefinition(d, []string{"parent", "child"}, "json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(out)).To(ContainSubstring(`"banner": "line 1\nline 2 < 3\n"`))
+		Expect(out).To(MatchJSON(`{"name":"child","description":"Greets world","type":"test","banner":"line 1\nline 2 < 3\n"}`))
+	})
+
+	It("Should keep false and empty values but not empty collections", func() {
+		d.Commands[0] = []byte(`{"name":"parent","description":"parent","type":"test","confirm_prompt":"","flags":[],"env":{},"x":null,"commands":[{"name":"child","type":"test","arguments":[{"name":"a","required":false,"default":""}]}]}`)
+
+		out, err := renderDefinition(d, []string{"parent"}, "json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(MatchJSON(`{"name":"parent","description":"parent","type":"test","confirm_prompt":"","commands":[{"name":"child","type":"test","arguments":[{"name":"a","required":false,"default":""}]}]}`))
+	})
+
+	It("Should fail for unknown commands and formats", func() {
+		_, err := renderDefinition(d, []string{"parent", "other"}, "yaml")
+		Expect(err).To(MatchError(`command "parent other" not found`))
+
+		_, err = renderDefinition(d, nil, "toml")
+		Expect(err).To(MatchError(`unknown format "toml"`))
+	})
+})
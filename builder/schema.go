@@ -0,0 +1,149 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"sort"
+)
+
+const schemaURL = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaProvider is implemented by types that describe their own schema, usually because they have custom JSON decoding
+type schemaProvider interface {
+	JSONSchema() map[string]any
+}
+
+var (
+	schemaProviderType = reflect.TypeOf((*schemaProvider)(nil)).Elem()
+	commandSchemas     = map[string]any{}
+	rawMessageType     = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaGenerator creates JSON Schema from Go types based on their JSON tags
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+// Schema creates a JSON Schema for application definitions including all registered command kinds
+func Schema() map[string]any {
+	pmu.Lock()
+	defer pmu.Unlock()
+
+	g := &schemaGenerator{defs: map[string]any{}}
+
+	var kinds []string
+	for kind := range commandPlugins {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var conditions []any
+	for _, kind := range kinds {
+		then := map[string]any{"type": "object"}
+		if def, ok := commandSchemas[kind]; ok {
+			then = g.schemaFor(reflect.TypeOf(def))
+		}
+
+		conditions = append(conditions, map[string]any{
+			"if":   map[string]any{"properties": map[string]any{"type": map[string]any{"const": kind}}},
+			"then": then,
+		})
+	}
+
+	command := map[string]any{
+		"type":     "object",
+		"required": []string{"name", "type"},
+		"properties": map[string]any{
+			"type": map[string]any{"enum": kinds},
+		},
+	}
+	if len(conditions) > 0 {
+		command["allOf"] = conditions
+	}
+
+	// commands using a template can set any setting, the template provides the type
+	g.defs["command"] = map[string]any{
+		"if": map[string]any{"required": []string{"use"}},
+		"then": map[string]any{
+			"type":     "object",
+			"required": []string{"name"},
+			"properties": map[string]any{
+				"use":  map[string]any{"type": "string"},
+				"with": map[string]any{"type": "object"},
+			},
+		},
+		"else": command,
+	}
+
+	schema := g.structSchema(reflect.TypeOf(Definition{}))
+	schema["$schema"] = schemaURL
+	schema["title"] = "Choria App Builder application definition"
+	schema["$defs"] = g.defs
+
+	return schema
+}
+
+// schemaFor creates the schema for t, named structs are added to the definitions and referenced
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == rawMessageType {
+		return map[string]any{"$ref": "#/$defs/command"}
+	}
+
+	if t.Implements(schemaProviderType) {
+		return reflect.Zero(t).Interface().(schemaProvider).JSONSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := g.defs[name]; !ok {
+			// placeholder stops recursive types like Transform from recursing forever
+			g.defs[name] = map[string]any{}
+			g.defs[name] = g.structSchema(t)
+		}
+
+		return map[string]any{"$ref": "#/$defs/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	for name, ft := range jsonFields(t) {
+		props[name] = g.schemaFor(ft)
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schema", func() {
+	Describe("schemaGenerator", func() {
+		It("Should generate schemas from json tags", func() {
+			type embedded struct {
+				Name  string `json:"name"`
+				Other string `json:"other"`
+			}
+
+			type test struct {
+				Other    int               `json:"other"`
+				Ignored  string            `json:"-"`
+				Count    uint              `json:"count,omitempty"`
+				Labels   map[string]string `json:"labels"`
+				Commands []json.RawMessage `json:"commands"`
+				Nested   *Transform        `json:"transform"`
+				internal string
+
+				embedded
+			}
+
+			g := &schemaGenerator{defs: map[string]any{}}
+			schema := g.structSchema(reflect.TypeOf(test{}))
+
+			Expect(schema["additionalProperties"]).To(BeFalse())
+			Expect(schema["properties"]).To(Equal(map[string]any{
+				"name":      map[string]any{"type": "string"},
+				"other":     map[string]any{"type": "integer"},
+				"count":     map[string]any{"type": "integer", "minimum": 0},
+				"labels":    map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+				"commands":  map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/command"}},
+				"transform": map[string]any{"$ref": "#/$defs/builder.Transform"},
+			}))
+
+			// Transform refers to itself in pipeline
+			transform := g.defs["builder.Transform"].(map[string]any)["properties"].(map[string]any)
+			Expect(transform["pipeline"]).To(Equal(map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/builder.Transform"}}))
+			Expect(transform["jq"]).To(Equal(map[string]any{"$ref": "#/$defs/builder.jqTransform"}))
+		})
+	})
+
+	Describe("Schema", func() {
+		It("Should include the definitions of registered commands", func() {
+			registerTestCommand()
+
+			j, err := json.Marshal(Schema())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(j)).To(ContainSubstring(`"if":{"properties":{"type":{"const":"test"}}},"then":{"$ref":"#/$defs/builder.testCommandDefinition"}`))
+		})
+	})
+})
@@ -203,7 +203,7 @@ var _ = Describe("Secrets", func() {
 				return []byte("resolved\n"), nil
 			}
 			var seen Secrets
-			action := runWrapper(secretCmd(), map[string]any{}, map[string]any{}, b, func(_ *fisk.ParseContext) error {
+			action := runWrapper(secretCmd(), map[string]any{}, map[string]any{}, nil, b, func(_ *fisk.ParseContext) error {
 				seen = b.Secrets()
 				return nil
 			})
@@ -221,7 +221,7 @@ var _ = Describe("Secrets", func() {
 			DeferCleanup(func() { os.Unsetenv("BUILDER_DRY_RUN") })
 
 			var seen Secrets
-			action := runWrapper(secretCmd(), map[string]any{}, map[string]any{}, b, func(_ *fisk.ParseContext) error {
+			action := runWrapper(secretCmd(), map[string]any{}, map[string]any{}, nil, b, func(_ *fisk.ParseContext) error {
 				seen = b.Secrets()
 				return nil
 			})
@@ -232,7 +232,7 @@ var _ = Describe("Secrets", func() {
 
 		It("should reset stale secrets for a command that declares none", func() {
 			b.secrets = Secrets{"stale": "value"}
-			action := runWrapper(GenericCommand{Name: "y"}, map[string]any{}, map[string]any{}, b, func(_ *fisk.ParseContext) error {
+			action := runWrapper(GenericCommand{Name: "y"}, map[string]any{}, map[string]any{}, nil, b, func(_ *fisk.ParseContext) error {
 				return nil
 			})
 			Expect(action(nil)).To(Succeed())
@@ -244,7 +244,7 @@ var _ = Describe("Secrets", func() {
 				return nil, errors.New("not signed in")
 			}
 			handlerRan := false
-			action := runWrapper(secretCmd(), map[string]any{}, map[string]any{}, b, func(_ *fisk.ParseContext) error {
+			action := runWrapper(secretCmd(), map[string]any{}, map[string]any{}, nil, b, func(_ *fisk.ParseContext) error {
 				handlerRan = true
 				return nil
 			})
@@ -0,0 +1,123 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// definitionSource is a parsed definition file used to find where items are defined
+type definitionSource struct {
+	file string
+	ast  *ast.File
+}
+
+func newDefinitionSource(file string) (*definitionSource, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parser.ParseBytes(b, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &definitionSource{file: file, ast: f}, nil
+}
+
+func (s *definitionSource) node(path string) ast.Node {
+	if s == nil {
+		return nil
+	}
+
+	p, err := yaml.PathString(path)
+	if err != nil {
+		return nil
+	}
+
+	n, err := p.FilterFile(s.ast)
+	if err != nil {
+		return nil
+	}
+
+	return n
+}
+
+// position is the line and column of the item at path like $.commands[1].flags[0], mappings are reported at their first key
+func (s *definitionSource) position(path string) (int, int, bool) {
+	n := s.node(path)
+	if n == nil {
+		return 0, 0, false
+	}
+
+	if m, ok := n.(*ast.MappingNode); ok && len(m.Values) > 0 {
+		n = m.Values[0].Key
+	}
+
+	tok := n.GetToken()
+	if tok == nil || tok.Position == nil {
+		return 0, 0, false
+	}
+
+	return tok.Position.Line, tok.Position.Column, true
+}
+
+// keyPosition is the line and column of the key of the item at path, falling back to the position of path when the
+// key can not be found
+func (s *definitionSource) keyPosition(path string) (int, int, bool) {
+	idx := strings.LastIndex(path, ".")
+	if idx == -1 {
+		return s.position(path)
+	}
+
+	parent, key := path[:idx], path[idx+1:]
+	if parent == "" {
+		parent = "$"
+	}
+
+	var values []*ast.MappingValueNode
+	switch n := s.node(parent).(type) {
+	case *ast.MappingNode:
+		values = n.Values
+	case *ast.MappingValueNode:
+		values = []*ast.MappingValueNode{n}
+	}
+
+	for _, v := range values {
+		tok := v.Key.GetToken()
+		if tok == nil || tok.Position == nil || tok.Value != key {
+			continue
+		}
+
+		return tok.Position.Line, tok.Position.Column, true
+	}
+
+	return s.position(path)
+}
+
+// location formats the position of path like file:line:col, empty when the position is unknown
+func (s *definitionSource) location(path string) string {
+	return s.formatPosition(s.position(path))
+}
+
+// keyLocation formats the position of the key of the item at path like file:line:col, empty when the position is unknown
+func (s *definitionSource) keyLocation(path string) string {
+	return s.formatPosition(s.keyPosition(path))
+}
+
+func (s *definitionSource) formatPosition(line int, col int, ok bool) string {
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%d:%d", s.file, line, col)
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// unknownKey is a key found in a definition that does not match any known property
+type unknownKey struct {
+	// file is the file holding the key when it is not the definition itself
+	file string
+	// path is the location of the key relative to the item being decoded like flags[0].nmae
+	path string
+	// suggestion is a known property with a similar name, empty when none is close
+	suggestion string
+}
+
+func (k unknownKey) String() string {
+	if k.suggestion == "" {
+		return fmt.Sprintf("unknown key %q", k.path)
+	}
+
+	return fmt.Sprintf("unknown key %q, did you mean %q", k.path, k.suggestion)
+}
+
+// UnmarshalCommand decodes the JSON definition j into target, failing when j has keys that target does not
+// support unless the definition allows unknown keys. Command plugins should use this to decode their definitions.
+func (b *AppBuilder) UnmarshalCommand(j json.RawMessage, target any) error {
+	err := json.Unmarshal(j, target)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+
+	// validate reports unknown keys along with their locations, so they are not fatal while loading
+	if b != nil && (b.allowUnknownKeys || b.deferUnknownKeys) {
+		return nil
+	}
+
+	unknown, err := findUnknownKeys(j, reflect.TypeOf(target))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+
+	return unknownKeysError(unknown)
+}
+
+func unknownKeysError(unknown []unknownKey) error {
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	var errs []string
+	for _, k := range unknown {
+		errs = append(errs, k.String())
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidDefinition, strings.Join(errs, ", "))
+}
+
+// commandUnknownKeys finds unknown keys in a command definition based on the definition registered for its type
+func commandUnknownKeys(kind string, j json.RawMessage) ([]unknownKey, error) {
+	pmu.Lock()
+	def, ok := commandSchemas[kind]
+	pmu.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	return findUnknownKeys(j, reflect.TypeOf(def))
+}
+
+// findUnknownKeys finds all keys in j that are not properties of t
+func findUnknownKeys(j []byte, t reflect.Type) ([]unknownKey, error) {
+	var v any
+	err := json.Unmarshal(j, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []unknownKey
+	walkUnknownKeys(t, v, "", &unknown)
+
+	return unknown, nil
+}
+
+func walkUnknownKeys(t reflect.Type, v any, path string, unknown *[]unknownKey) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// sub commands are decoded, and checked, by their own plugin
+	if t == rawMessageType {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+
+		fields := jsonFields(t)
+
+		var keys []string
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			ft, ok := fields[k]
+			if !ok {
+				*unknown = append(*unknown, unknownKey{path: joinKeyPath(path, k), suggestion: suggestKey(k, fields)})
+				continue
+			}
+
+			walkUnknownKeys(ft, m[k], joinKeyPath(path, k), unknown)
+		}
+
+	case reflect.Slice, reflect.Array:
+		items, ok := v.([]any)
+		if !ok {
+			return
+		}
+
+		for i, item := range items {
+			walkUnknownKeys(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i), unknown)
+		}
+
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+
+		for k, item := range m {
+			walkUnknownKeys(t.Elem(), item, joinKeyPath(path, k), unknown)
+		}
+	}
+}
+
+func joinKeyPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// jsonFields is the JSON properties of t and their types, flattening embedded structs like encoding/json does where
+// fields of the outer struct take precedence over embedded ones
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	addJSONFields(t, fields, false)
+
+	return fields
+}
+
+func addJSONFields(t reflect.Type, fields map[string]reflect.Type, embedded bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addJSONFields(ft, fields, true)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		if _, ok := fields[name]; ok && embedded {
+			continue
+		}
+
+		fields[name] = f.Type
+	}
+}
+
+// suggestKey finds the known field closest to key, empty when none is similar enough to be a likely typo
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	var (
+		best     string
+		bestDist = max(2, len(key)/4) + 1
+	)
+
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		d := editDistance(strings.ToLower(key), name)
+		if d < bestDist {
+			best = name
+			bestDist = d
+		}
+	}
+
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
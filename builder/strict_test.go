@@ -0,0 +1,46 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Strict decoding", func() {
+	Describe("UnmarshalCommand", func() {
+		It("Should reject unknown keys with suggestions", func() {
+			def := &GenericCommand{}
+			err := (&AppBuilder{}).UnmarshalCommand([]byte(`{"name":"x","descripton":"y","flags":[{"name":"f","enum":["a"],"requried":true}],"other":1}`), def)
+			Expect(err).To(MatchError(`invalid definition: unknown key "descripton", did you mean "description", unknown key "flags[0].requried", did you mean "required", unknown key "other"`))
+			Expect(def.Name).To(Equal("x"))
+		})
+
+		It("Should accept known keys", func() {
+			def := &GenericCommand{}
+			err := (&AppBuilder{}).UnmarshalCommand([]byte(`{"name":"x","description":"y","type":"exec","flags":[{"name":"f","required":true}]}`), def)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should support allowing unknown keys", func() {
+			def := &GenericCommand{}
+			err := (&AppBuilder{allowUnknownKeys: true}).UnmarshalCommand([]byte(`{"name":"x","future":"y"}`), def)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(def.Name).To(Equal("x"))
+		})
+	})
+
+	Describe("suggestKey", func() {
+		It("Should only suggest similar keys", func() {
+			fields := jsonFields(reflect.TypeOf(GenericCommand{}))
+			Expect(suggestKey("enviroment", map[string]reflect.Type{"environment": nil, "env": nil})).To(Equal("environment"))
+			Expect(suggestKey("descripton", fields)).To(Equal("description"))
+			Expect(suggestKey("Name", fields)).To(Equal("name"))
+			Expect(suggestKey("something", fields)).To(Equal(""))
+		})
+	})
+})
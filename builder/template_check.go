@@ -0,0 +1,298 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateChecker is implemented by commands that can check their templates, problems are reported by validate rather
+// than when the application starts so definitions that worked before keep working
+type TemplateChecker interface {
+	// CheckTemplates finds templates using undefined functions or referencing undeclared arguments and flags
+	CheckTemplates() []string
+}
+
+// templateReferenceKinds are the parts of the template state that references are found for
+var templateReferenceKinds = []string{"Arguments", "Flags", "Secrets"}
+
+// ValidateTemplates parses templates, keyed by the property they are defined in, using the functions RenderTemplate
+// would use with opts and ensures they only reference arguments and flags declared by cmd. The banner of cmd and the
+// templates in transform are also checked. The result is suitable to return from CheckTemplates().
+func (b *AppBuilder) ValidateTemplates(cmd *GenericCommand, transform *Transform, templates map[string]string, opts ...TemplateOption) []string {
+	o := newTemplateOpts(opts...)
+
+	funcs := b.TemplateFuncs(o.sprig)
+	for n, f := range o.funcs {
+		funcs[n] = f
+	}
+
+	var errs []string
+
+	check := func(property string, body string, funcs template.FuncMap) {
+		refs, err := templateReferences(body, funcs)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid template in %s: %v", property, err))
+			return
+		}
+
+		for _, ref := range refs {
+			switch {
+			case ref.kind == "Flags" && !cmd.hasFlag(ref.name) && !b.inheritsFlag(ref.name):
+				errs = append(errs, fmt.Sprintf("%s references undeclared flag %q", property, ref.name))
+			case ref.kind == "Arguments" && !cmd.hasArgument(ref.name):
+				errs = append(errs, fmt.Sprintf("%s references undeclared argument %q", property, ref.name))
+			}
+		}
+	}
+
+	var properties []string
+	for property := range templates {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+
+	for _, property := range properties {
+		check(property, templates[property], funcs)
+	}
+
+	if cmd.Banner != "" {
+		check("banner", cmd.Banner, b.TemplateFuncs(true))
+	}
+
+	if transform != nil {
+		tt := transform.templates("transform")
+
+		properties = nil
+		for property := range tt {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+
+		for _, property := range properties {
+			check(property, tt[property].body, b.TemplateFuncs(tt[property].sprig))
+		}
+	}
+
+	return errs
+}
+
+func (c *GenericCommand) hasFlag(name string) bool {
+	// added to all commands with a confirmation prompt
+	if name == "prompt" && c.ConfirmPrompt != "" {
+		return true
+	}
+
+	for _, f := range c.Flags {
+		if f.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *GenericCommand) hasArgument(name string) bool {
+	for _, a := range c.Arguments {
+		if a.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transformTemplate is a template used by a transform
+type transformTemplate struct {
+	body  string
+	sprig bool
+}
+
+// templates are all templates in t rendered using the command state, keyed by their location below prefix
+func (t *Transform) templates(prefix string) map[string]transformTemplate {
+	res := map[string]transformTemplate{}
+
+	add := func(property string, body string, sprig bool) {
+		if body != "" {
+			res[prefix+"."+property] = transformTemplate{body: body, sprig: sprig}
+		}
+	}
+
+	add("query", t.Query, false)
+	if t.JQ != nil {
+		add("jq.query", t.JQ.Query, false)
+	}
+	if t.LineGraph != nil {
+		add("line_graph.caption", t.LineGraph.Caption, false)
+	}
+	if t.BarGraph != nil {
+		add("bar_graph.caption", t.BarGraph.Caption, false)
+	}
+	if t.Template != nil {
+		add("template.source", t.Template.Source, false)
+	}
+	if t.Report != nil {
+		add("report.name", t.Report.Name, false)
+		add("report.source_file", t.Report.SourceFile, false)
+	}
+	if t.WriteFile != nil {
+		add("write_file.file", t.WriteFile.File, false)
+	}
+	if t.Scaffold != nil {
+		add("scaffold.source_directory", t.Scaffold.SourceDirectory, true)
+		add("scaffold.target", t.Scaffold.TargetDirectory, true)
+	}
+	if t.CCMManifest != nil {
+		add("ccm_manifest.manifest", t.CCMManifest.Manifest, false)
+	}
+
+	for i, p := range t.Pipeline {
+		for k, v := range p.templates(fmt.Sprintf("%s.pipeline[%d]", prefix, i)) {
+			res[k] = v
+		}
+	}
+
+	return res
+}
+
+// templateReference is a reference to a named item in the template state like .Flags.name
+type templateReference struct {
+	kind string
+	name string
+}
+
+// templateReferences parses body and finds all references to arguments, flags and secrets, when funcs is nil the
+// functions used are not checked
+func templateReferences(body string, funcs template.FuncMap) ([]templateReference, error) {
+	var root *parse.ListNode
+
+	if funcs == nil {
+		tree := parse.New("choria")
+		tree.Mode = parse.SkipFuncCheck
+
+		_, err := tree.Parse(body, "", "", map[string]*parse.Tree{})
+		if err != nil {
+			return nil, err
+		}
+		root = tree.Root
+	} else {
+		tmpl, err := template.New("choria").Funcs(funcs).Parse(body)
+		if err != nil {
+			return nil, err
+		}
+		root = tmpl.Tree.Root
+	}
+
+	found := map[templateReference]struct{}{}
+	walkTemplateNode(root, true, found)
+
+	var refs []templateReference
+	for ref := range found {
+		refs = append(refs, ref)
+	}
+	sortTemplateReferences(refs)
+
+	return refs, nil
+}
+
+func sortTemplateReferences(refs []templateReference) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].kind == refs[j].kind {
+			return refs[i].name < refs[j].name
+		}
+		return refs[i].kind < refs[j].kind
+	})
+}
+
+// walkTemplateNode finds references in n, rootDot indicates if dot is the template state, it is not inside range and with
+func walkTemplateNode(n parse.Node, rootDot bool, found map[templateReference]struct{}) {
+	switch node := n.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, c := range node.Nodes {
+			walkTemplateNode(c, rootDot, found)
+		}
+
+	case *parse.ActionNode:
+		walkTemplateNode(node.Pipe, rootDot, found)
+
+	case *parse.IfNode:
+		walkTemplateNode(node.Pipe, rootDot, found)
+		walkTemplateNode(node.List, rootDot, found)
+		walkTemplateNode(node.ElseList, rootDot, found)
+
+	case *parse.RangeNode:
+		walkTemplateNode(node.Pipe, rootDot, found)
+		walkTemplateNode(node.List, false, found)
+		walkTemplateNode(node.ElseList, rootDot, found)
+
+	case *parse.WithNode:
+		walkTemplateNode(node.Pipe, rootDot, found)
+		walkTemplateNode(node.List, false, found)
+		walkTemplateNode(node.ElseList, rootDot, found)
+
+	case *parse.TemplateNode:
+		walkTemplateNode(node.Pipe, rootDot, found)
+
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, c := range node.Cmds {
+			walkTemplateNode(c, rootDot, found)
+		}
+
+	case *parse.CommandNode:
+		// index .Flags "name"
+		if len(node.Args) >= 3 {
+			if id, ok := node.Args[0].(*parse.IdentifierNode); ok && id.Ident == "index" {
+				if s, ok := node.Args[2].(*parse.StringNode); ok {
+					if kind := templateStateKind(node.Args[1], rootDot); kind != "" {
+						found[templateReference{kind: kind, name: s.Text}] = struct{}{}
+					}
+				}
+			}
+		}
+
+		for _, arg := range node.Args {
+			walkTemplateNode(arg, rootDot, found)
+		}
+
+	case *parse.FieldNode:
+		if rootDot && len(node.Ident) >= 2 && slices.Contains(templateReferenceKinds, node.Ident[0]) {
+			found[templateReference{kind: node.Ident[0], name: node.Ident[1]}] = struct{}{}
+		}
+
+	case *parse.VariableNode:
+		if len(node.Ident) >= 3 && node.Ident[0] == "$" && slices.Contains(templateReferenceKinds, node.Ident[1]) {
+			found[templateReference{kind: node.Ident[1], name: node.Ident[2]}] = struct{}{}
+		}
+
+	case *parse.ChainNode:
+		walkTemplateNode(node.Node, rootDot, found)
+	}
+}
+
+// templateStateKind is the kind of state n refers to when it is exactly .Flags, $.Flags and similar
+func templateStateKind(n parse.Node, rootDot bool) string {
+	switch node := n.(type) {
+	case *parse.FieldNode:
+		if rootDot && len(node.Ident) == 1 && slices.Contains(templateReferenceKinds, node.Ident[0]) {
+			return node.Ident[0]
+		}
+	case *parse.VariableNode:
+		if len(node.Ident) == 2 && node.Ident[0] == "$" && slices.Contains(templateReferenceKinds, node.Ident[1]) {
+			return node.Ident[1]
+		}
+	}
+
+	return ""
+}
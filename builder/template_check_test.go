@@ -0,0 +1,64 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"text/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Template checks", func() {
+	Describe("templateReferences", func() {
+		It("Should find references to the template state", func() {
+			refs, err := templateReferences(`{{ .Flags.one }} {{ if .Arguments.two }}{{ index .Flags "three" }}{{ end }} {{ $.Secrets.four }} {{ range .Flags.five }}{{ .Flags.ignored }}{{ $.Arguments.six }}{{ end }} {{ with .Config.x }}{{ .Flags.ignored }}{{ else }}{{ .Flags.seven }}{{ end }}`, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refs).To(Equal([]templateReference{
+				{kind: "Arguments", name: "six"},
+				{kind: "Arguments", name: "two"},
+				{kind: "Flags", name: "five"},
+				{kind: "Flags", name: "one"},
+				{kind: "Flags", name: "seven"},
+				{kind: "Flags", name: "three"},
+				{kind: "Secrets", name: "four"},
+			}))
+		})
+
+		It("Should check functions when given", func() {
+			_, err := templateReferences(`{{ missing .Flags.x }}`, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = templateReferences(`{{ missing .Flags.x }}`, template.FuncMap{})
+			Expect(err).To(MatchError(ContainSubstring(`function "missing" not defined`)))
+		})
+	})
+
+	Describe("ValidateTemplates", func() {
+		It("Should report undeclared references and invalid templates", func() {
+			cmd := &GenericCommand{
+				Banner:        "{{ .Arguments.missing }}",
+				ConfirmPrompt: "Really?",
+				Flags:         []GenericFlag{{Name: "known"}},
+				Arguments:     []GenericArgument{{Name: "arg"}},
+			}
+
+			transform := &Transform{Pipeline: []Transform{{JQ: &jqTransform{Query: "{{ .Flags.other }}"}}}}
+
+			errs := (&AppBuilder{}).ValidateTemplates(cmd, transform, map[string]string{
+				"command": "{{ .Flags.known }} {{ .Flags.prompt }} {{ .Arguments.arg }} {{ .Flags.verbos }}",
+				"script":  "{{ Custom }} {{ unknown }}",
+				"dir":     "{{ Custom | upper }}",
+			}, WithSprig(), WithFuncs(template.FuncMap{"Custom": func() string { return "" }}))
+
+			Expect(errs).To(Equal([]string{
+				`command references undeclared flag "verbos"`,
+				`invalid template in script: template: choria:1: function "unknown" not defined`,
+				`banner references undeclared argument "missing"`,
+				`transform.pipeline[0].jq.query references undeclared flag "other"`,
+			}))
+		})
+	})
+})
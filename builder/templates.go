@@ -34,6 +34,8 @@ func dereferenceArgsOrFlags(input map[string]any) map[string]any {
 			res[k] = e.Float()
 		case reflect.String:
 			res[k] = e.String()
+		case reflect.Slice, reflect.Map, reflect.Interface:
+			res[k] = e.Interface()
 		default:
 			res[k] = fmt.Sprintf("%v", e)
 		}
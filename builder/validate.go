@@ -0,0 +1,177 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// definitionValidator validates a definition and all its commands, noting where in the source files problems are found
+type definitionValidator struct {
+	b       *AppBuilder
+	d       *Definition
+	sources map[string]*definitionSource
+	errs    []string
+}
+
+func newDefinitionValidator(b *AppBuilder) *definitionValidator {
+	return &definitionValidator{
+		b:       b,
+		sources: map[string]*definitionSource{},
+	}
+}
+
+// source parses file once, nil when it cannot be parsed in which case errors are reported without locations
+func (v *definitionValidator) source(file string) *definitionSource {
+	src, ok := v.sources[file]
+	if ok {
+		return src
+	}
+
+	src, err := newDefinitionSource(file)
+	if err != nil {
+		v.b.log.Debugf("Could not parse %s to determine error locations: %v", file, err)
+		src = nil
+	}
+	v.sources[file] = src
+
+	return src
+}
+
+// addError records msg prefixed with the file, line and column of path in src when known
+func (v *definitionValidator) addError(src *definitionSource, path string, msg string) {
+	if loc := src.location(path); loc != "" {
+		msg = fmt.Sprintf("%s: %s", loc, msg)
+	}
+
+	v.errs = append(v.errs, msg)
+}
+
+// addKeyError records msg prefixed with the file, line and column of the key of path in src when known
+func (v *definitionValidator) addKeyError(src *definitionSource, path string, msg string) {
+	if loc := src.keyLocation(path); loc != "" {
+		msg = fmt.Sprintf("%s: %s", loc, msg)
+	}
+
+	v.errs = append(v.errs, msg)
+}
+
+// locate finds the source and path where the command at path, its location after expanding includes, is defined
+// along with the location of the include that loaded it
+func (v *definitionValidator) locate(path string) (*definitionSource, string, string) {
+	if v.d != nil {
+		if o, ok := v.d.origins[path]; ok {
+			return v.source(o.file), o.path, o.includedFrom
+		}
+	}
+
+	return v.source(v.b.definitionPath), path, ""
+}
+
+// addCommandError records msg for property, empty for the command itself, of the command at path
+func (v *definitionValidator) addCommandError(path string, property string, msg string) {
+	src, p, msg := v.commandError(path, property, msg)
+	v.addError(src, p, msg)
+}
+
+// addCommandKeyError records msg at the key of property of the command at path
+func (v *definitionValidator) addCommandKeyError(path string, property string, msg string) {
+	src, p, msg := v.commandError(path, property, msg)
+	v.addKeyError(src, p, msg)
+}
+
+// commandError finds the source and path of property of the command at path, noting in msg where it was included from
+func (v *definitionValidator) commandError(path string, property string, msg string) (*definitionSource, string, string) {
+	src, p, from := v.locate(path)
+	if property != "" {
+		p = p + "." + property
+	}
+
+	if from != "" {
+		msg = fmt.Sprintf("%s (included from %s)", msg, from)
+	}
+
+	return src, p, msg
+}
+
+func (v *definitionValidator) validateDefinition(d *Definition) {
+	v.d = d
+
+	err := d.Validate(v.b.log)
+	if err != nil {
+		v.addError(v.source(v.b.definitionPath), "$", err.Error())
+	}
+
+	if !v.b.allowUnknownKeys {
+		for _, k := range d.unknownKeys {
+			file := k.file
+			if file == "" {
+				file = v.b.definitionPath
+			}
+
+			v.addKeyError(v.source(file), "$."+k.path, k.String())
+		}
+	}
+
+	v.b.withInheritedFlags("the application", d.Flags, func() error {
+		for i, c := range d.commands {
+			v.validateCommand([]string{"root"}, fmt.Sprintf("$.commands[%d]", i), d.Commands[i], c)
+		}
+
+		return nil
+	})
+}
+
+// validateCommand validates c, defined by raw, found at path and recursively all its sub commands
+func (v *definitionValidator) validateCommand(bread []string, path string, raw json.RawMessage, c Command) {
+	bread = append(append([]string{}, bread...), c.String())
+
+	v.b.log.Debugf("Validating %s", c)
+	err := c.Validate(v.b.log)
+	if err != nil {
+		v.addCommandError(path, "", fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), err))
+	}
+
+	if tc, ok := c.(TemplateChecker); ok {
+		for _, msg := range tc.CheckTemplates() {
+			v.addCommandError(path, "", fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), msg))
+		}
+	}
+
+	for _, msg := range v.b.inheritedFlagErrors(raw) {
+		v.addCommandError(path, "", fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), msg))
+	}
+
+	if !v.b.allowUnknownKeys {
+		unknown, err := commandUnknownKeys(gjson.GetBytes(raw, "type").String(), raw)
+		if err != nil {
+			v.addCommandError(path, "", fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), err))
+		}
+
+		for _, k := range unknown {
+			v.addCommandKeyError(path, k.path, fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), k))
+		}
+	}
+
+	v.b.withCommandFlags(c, func() error {
+		for i, sub := range c.SubCommands() {
+			path := fmt.Sprintf("%s.commands[%d]", path, i)
+
+			sc, err := v.b.createCommand(sub)
+			if err != nil {
+				v.addCommandError(path, "", fmt.Sprintf("%s: %s", strings.Join(bread, " -> "), err))
+				continue
+			}
+
+			v.validateCommand(bread, path, sub, sc)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/choria-io/fisk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// testCommand is a minimal command plugin used to test the builder without the standard commands
+type testCommand struct {
+	def testCommandDefinition
+}
+
+type testCommandDefinition struct {
+	GenericCommand
+	GenericSubCommands
+}
+
+func (c *testCommand) CreateCommand(app KingpinCommand) (*fisk.CmdClause, error) {
+	return app.Command(c.def.Name, c.def.Description), nil
+}
+
+func (c *testCommand) SubCommands() []json.RawMessage { return c.def.Commands }
+func (c *testCommand) Validate(log Logger) error      { return c.def.GenericCommand.Validate(log) }
+func (c *testCommand) String() string                 { return fmt.Sprintf("%s (test)", c.def.Name) }
+
+func registerTestCommand() {
+	RegisterCommand("test", func(b *AppBuilder, j json.RawMessage, _ Logger) (Command, error) {
+		cmd := &testCommand{}
+		err := b.UnmarshalCommand(j, &cmd.def)
+		if err != nil {
+			return nil, err
+		}
+
+		return cmd, nil
+	}, &testCommandDefinition{})
+}
+
+// writeTestFile writes body to name in dir, creating any directories needed, and returns the path written
+func writeTestFile(dir string, name string, body string) string {
+	file := filepath.Join(dir, name)
+	Expect(os.MkdirAll(filepath.Dir(file), 0700)).To(Succeed())
+	Expect(os.WriteFile(file, []byte(body), 0600)).To(Succeed())
+
+	return file
+}
+
+// loadTestDefinition loads the definition in file using a builder for it configured using opts
+func loadTestDefinition(file string, opts ...Option) (*AppBuilder, *Definition, error) {
+	b, err := New(context.Background(), "test", append([]Option{WithAppDefinitionFile(file), WithLogger(NoopLogger{})}, opts...)...)
+	Expect(err).ToNot(HaveOccurred())
+
+	d, err := b.loadDefinition(file)
+
+	return b, d, err
+}
+
+// withDeferredUnknownKeys loads definitions with unknown keys so validate can report them with their locations
+func withDeferredUnknownKeys() Option {
+	return func(b *AppBuilder) error {
+		b.deferUnknownKeys = true
+		return nil
+	}
+}
+
+// templatedCommand is a test command that checks its templates
+type templatedCommand struct {
+	testCommand
+	b *AppBuilder
+}
+
+func (c *templatedCommand) CheckTemplates() []string {
+	return c.b.ValidateTemplates(&c.def.GenericCommand, nil, nil)
+}
+
+var _ = Describe("Validate", func() {
+	BeforeEach(func() {
+		registerTestCommand()
+		RegisterCommand("templated", func(b *AppBuilder, j json.RawMessage, _ Logger) (Command, error) {
+			cmd := &templatedCommand{b: b}
+			err := b.UnmarshalCommand(j, &cmd.def)
+			if err != nil {
+				return nil, err
+			}
+
+			return cmd, nil
+		}, nil)
+	})
+
+	It("Should report errors with their location", func() {
+		def := filepath.Join(GinkgoT().TempDir(), "test-app.yaml")
+		Expect(os.WriteFile(def, []byte(`name: test
+version: 1.0.0
+author: ginkgo
+
+commands:
+  - name: one
+    description: one
+    type: test
+    commands:
+      - name: two
+        type: test
+      - name: three
+        description: three
+        type: unknown
+`), 0600)).To(Succeed())
+
+		b, err := New(context.Background(), "test", WithAppDefinitionFile(def), WithLogger(NoopLogger{}))
+		Expect(err).ToNot(HaveOccurred())
+
+		d, err := b.loadDefinition(def)
+		Expect(err).ToNot(HaveOccurred())
+
+		v := newDefinitionValidator(b)
+		v.validateDefinition(d)
+		Expect(v.errs).To(Equal([]string{
+			fmt.Sprintf("%s:1:1: invalid definition: application: description is required", def),
+			fmt.Sprintf("%s:10:9: root -> one (test) -> two (test): description is required", def),
+			fmt.Sprintf("%s:12:9: root -> one (test): unknown plugin: unknown", def),
+		}))
+	})
+
+	It("Should report unknown keys with their location", func() {
+		def := filepath.Join(GinkgoT().TempDir(), "test-app.yaml")
+		Expect(os.WriteFile(def, []byte(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+auhtor: ginkgo
+
+commands:
+  - name: one
+    description: one
+    type: test
+    commands:
+      - name: two
+        description: two
+        type: test
+        flags:
+          - name: f
+            description: f
+            defualt: x
+        something: else
+`), 0600)).To(Succeed())
+
+		b, err := New(context.Background(), "test", WithAppDefinitionFile(def), WithLogger(NoopLogger{}))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = b.loadDefinition(def)
+		Expect(err).To(MatchError(`invalid definition: unknown key "auhtor", did you mean "author"`))
+
+		b.deferUnknownKeys = true
+		d, err := b.loadDefinition(def)
+		Expect(err).ToNot(HaveOccurred())
+
+		v := newDefinitionValidator(b)
+		v.validateDefinition(d)
+		Expect(v.errs).To(Equal([]string{
+			fmt.Sprintf(`%s:5:1: unknown key "auhtor", did you mean "author"`, def),
+			fmt.Sprintf(`%s:18:13: root -> one (test) -> two (test): unknown key "flags[0].defualt", did you mean "default"`, def),
+			fmt.Sprintf(`%s:19:9: root -> one (test) -> two (test): unknown key "something"`, def),
+		}))
+	})
+
+	It("Should allow unknown keys when requested", func() {
+		def := filepath.Join(GinkgoT().TempDir(), "test-app.yaml")
+		Expect(os.WriteFile(def, []byte(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+allow_unknown_keys: true
+future: setting
+
+commands:
+  - name: one
+    description: one
+    type: test
+    future: setting
+`), 0600)).To(Succeed())
+
+		b, err := New(context.Background(), "test", WithAppDefinitionFile(def), WithLogger(NoopLogger{}))
+		Expect(err).ToNot(HaveOccurred())
+
+		d, err := b.loadDefinition(def)
+		Expect(err).ToNot(HaveOccurred())
+
+		v := newDefinitionValidator(b)
+		v.validateDefinition(d)
+		Expect(v.errs).To(BeEmpty())
+	})
+
+	It("Should report template problems without failing to load the application", func() {
+		def := filepath.Join(GinkgoT().TempDir(), "test-app.yaml")
+		Expect(os.WriteFile(def, []byte(`name: test
+description: test
+version: 1.0.0
+author: ginkgo
+
+commands:
+  - name: one
+    description: one
+    type: templated
+    banner: "{{ .Flags.missing }}"
+`), 0600)).To(Succeed())
+
+		b, err := New(context.Background(), "test", WithAppDefinitionFile(def), WithLogger(NoopLogger{}))
+		Expect(err).ToNot(HaveOccurred())
+
+		b.def, err = b.loadDefinition(def)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = b.createAppCLI()
+		Expect(err).ToNot(HaveOccurred())
+
+		v := newDefinitionValidator(b)
+		v.validateDefinition(b.def)
+		Expect(v.errs).To(Equal([]string{
+			fmt.Sprintf(`%s:7:5: root -> one (test): banner references undeclared flag "missing"`, def),
+		}))
+	})
+})
@@ -42,11 +42,11 @@ type CCMManifest struct {
 }
 
 func Register() error {
-	return builder.RegisterCommand("exec", NewCCMManifest)
+	return builder.RegisterCommand("ccm_manifest", NewCCMManifest, &Command{})
 }
 
 func MustRegister() {
-	builder.MustRegisterCommand("ccm_manifest", NewCCMManifest)
+	builder.MustRegisterCommand("ccm_manifest", NewCCMManifest, &Command{})
 }
 
 func NewCCMManifest(b *builder.AppBuilder, j json.RawMessage, log builder.Logger) (builder.Command, error) {
@@ -59,9 +59,9 @@ func NewCCMManifest(b *builder.AppBuilder, j json.RawMessage, log builder.Logger
 		flags:     map[string]any{},
 	}
 
-	err := json.Unmarshal(j, manifest.def)
+	err := b.UnmarshalCommand(j, manifest.def)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", builder.ErrInvalidDefinition, err)
+		return nil, err
 	}
 
 	return manifest, nil
@@ -89,6 +89,10 @@ func (r *CCMManifest) Validate(log builder.Logger) error {
 	return nil
 }
 
+func (r *CCMManifest) CheckTemplates() []string {
+	return r.b.ValidateTemplates(&r.def.GenericCommand, r.def.Transform, map[string]string{"manifest": r.def.Manifest})
+}
+
 func (r *CCMManifest) SubCommands() []json.RawMessage {
 	return r.def.Commands
 }
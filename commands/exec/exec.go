@@ -5,14 +5,17 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
 	"text/template"
 	"time"
 
@@ -37,9 +40,19 @@ type Command struct {
 	Transform   *builder.Transform `json:"transform"`
 	Script      string             `json:"script"`
 	Shell       string             `json:"shell"`
+	Interpreter string             `json:"interpreter"`
+	Shebang     bool               `json:"shebang"`
 	Backoff     *Backoff           `json:"backoff"`
 	WorkingDir  string             `json:"dir"`
 	NoHelper    bool               `json:"no_helper"`
+	Hosts       *Hosts             `json:"hosts"`
+	Limits      *Limits            `json:"limits"`
+	Grace       string             `json:"shutdown_grace"`
+	Interactive bool               `json:"interactive"`
+	LogFile     *LogFile           `json:"log_file"`
+
+	CleanEnvironment bool     `json:"clean_environment"`
+	EnvironmentAllow []string `json:"environment_allow"`
 
 	builder.GenericSubCommands
 	builder.GenericCommand
@@ -55,16 +68,20 @@ type Exec struct {
 	ctx        context.Context
 	log        builder.Logger
 	bo         *policy
+	grace      time.Duration
 	helperPath string
+	tempDir    string
+	started    time.Time
+	logOutput  io.Writer
 	b          *builder.AppBuilder
 }
 
 func Register() error {
-	return builder.RegisterCommand("exec", NewExecCommand)
+	return builder.RegisterCommand("exec", NewExecCommand, &Command{})
 }
 
 func MustRegister() {
-	builder.MustRegisterCommand("exec", NewExecCommand)
+	builder.MustRegisterCommand("exec", NewExecCommand, &Command{})
 }
 
 var (
@@ -86,9 +103,9 @@ func NewExecCommand(b *builder.AppBuilder, j json.RawMessage, log builder.Logger
 		flags:     map[string]any{},
 	}
 
-	err := json.Unmarshal(j, exec.def)
+	err := b.UnmarshalCommand(j, exec.def)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", builder.ErrInvalidDefinition, err)
+		return nil, err
 	}
 
 	err = exec.configureBackoff()
@@ -96,6 +113,11 @@ func NewExecCommand(b *builder.AppBuilder, j json.RawMessage, log builder.Logger
 		return nil, err
 	}
 
+	err = exec.configureGrace()
+	if err != nil {
+		return nil, err
+	}
+
 	return exec, nil
 }
 
@@ -131,6 +153,23 @@ func (r *Exec) configureBackoff() error {
 	return nil
 }
 
+func (r *Exec) configureGrace() error {
+	r.grace = time.Second
+
+	if r.def.Grace == "" {
+		return nil
+	}
+
+	grace, err := time.ParseDuration(r.def.Grace)
+	if err != nil {
+		return fmt.Errorf("%w: invalid shutdown_grace: %v", builder.ErrInvalidDefinition, err)
+	}
+
+	r.grace = grace
+
+	return nil
+}
+
 func (r *Exec) String() string { return fmt.Sprintf("%s (exec)", r.def.Name) }
 
 func (r *Exec) Validate(log builder.Logger) error {
@@ -158,6 +197,23 @@ func (r *Exec) Validate(log builder.Logger) error {
 		errs = append(errs, "only one of command or script is allowed")
 	}
 
+	if r.def.Interpreter != "" && r.def.Script == "" {
+		errs = append(errs, "interpreter requires a script")
+	}
+
+	if r.def.Interpreter != "" && r.def.Shell != "" {
+		errs = append(errs, "only one of interpreter or shell is allowed")
+	}
+
+	if r.def.Shebang {
+		switch {
+		case !strings.HasPrefix(r.def.Script, "#!"):
+			errs = append(errs, "shebang requires a script starting with a #! line")
+		case r.def.Interpreter != "" || r.def.Shell != "":
+			errs = append(errs, "shebang can not be combined with interpreter or shell")
+		}
+	}
+
 	if r.def.Transform != nil {
 		err := r.def.Transform.Validate(log)
 		if err != nil {
@@ -165,6 +221,31 @@ func (r *Exec) Validate(log builder.Logger) error {
 		}
 	}
 
+	if r.def.Hosts != nil {
+		err := r.def.Hosts.Validate()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if r.def.LogFile != nil {
+		err := r.def.LogFile.Validate()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if r.def.Limits != nil {
+		err := r.def.Limits.Validate()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(r.def.EnvironmentAllow) > 0 && !r.def.CleanEnvironment {
+		errs = append(errs, "environment_allow requires clean_environment")
+	}
+
 	if r.def.Backoff != nil {
 		if r.def.Backoff.PolicySteps < 2 {
 			errs = append(errs, fmt.Sprintf("invalid backoff policy steps: '%d'", r.def.Backoff.PolicySteps))
@@ -178,6 +259,10 @@ func (r *Exec) Validate(log builder.Logger) error {
 	return nil
 }
 
+func (r *Exec) CheckTemplates() []string {
+	return r.b.ValidateTemplates(&r.def.GenericCommand, r.def.Transform, r.templates(), builder.WithSprig(), builder.WithFuncs(r.templateFuncs("")))
+}
+
 func (r *Exec) SubCommands() []json.RawMessage {
 	return r.def.Commands
 }
@@ -202,6 +287,76 @@ func (r *Exec) logCommand(cmd string, args []string, env []string) {
 	}
 }
 
+// run starts cmd, in its own process group unless it shares our terminal, with any resource limits and waits for it
+// to complete. On cancellation the whole process group is signalled and then killed once the grace period passed
+func (r *Exec) run(cmd *exec.Cmd) error {
+	if r.def.Limits != nil {
+		err := r.def.Limits.wrap(cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	restore, group := setProcessGroup(cmd, r.def.Interactive)
+	defer restore()
+
+	// terminateProcessGroup handles cancellation rather than only killing the direct child
+	cmd.Cancel = func() error { return nil }
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	terminated := make(chan struct{})
+	stop := context.AfterFunc(r.ctx, func() {
+		defer close(terminated)
+
+		release := r.b.DelayInterruptExit()
+		defer release()
+
+		r.terminateProcessGroup(cmd.Process.Pid, group)
+	})
+
+	err = cmd.Wait()
+	if !stop() {
+		<-terminated
+	}
+
+	return err
+}
+
+// terminateProcessGroup forwards the signal that interrupted us to the process group led by pid, or only to pid when
+// it is not in its own group, falling back to SIGTERM, and kills it if it did not exit within the grace period
+func (r *Exec) terminateProcessGroup(pid int, group bool) {
+	sig := builder.InterruptSignal(r.ctx)
+	if sig == nil {
+		sig = syscall.SIGTERM
+	}
+
+	r.log.Debugf("Sending %s to process %d", sig, pid)
+	signalProcess(pid, group, sig)
+
+	deadline := time.Now().Add(r.grace)
+	for processRunning(pid, group) && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if processRunning(pid, group) {
+		r.log.Warnf("Killing process %d after %v grace period", pid, r.grace)
+		killProcess(pid, group)
+	}
+}
+
+// teeLog writes to the log file in addition to w when a log file is configured
+func (r *Exec) teeLog(w io.Writer) io.Writer {
+	if r.logOutput == nil {
+		return w
+	}
+
+	return io.MultiWriter(w, r.logOutput)
+}
+
 func (r *Exec) runInTerminal(cmd string, args []string, env []string) error {
 	r.logCommand(cmd, args, env)
 
@@ -210,13 +365,13 @@ func (r *Exec) runInTerminal(cmd string, args []string, env []string) error {
 	}
 
 	run := exec.CommandContext(r.ctx, cmd, args...)
-	run.Env = append(os.Environ(), env...)
+	run.Env = append(r.baseEnvironment(), env...)
 	run.Stdin = os.Stdin
-	run.Stdout = r.b.Stdout()
-	run.Stderr = r.b.Stderr()
+	run.Stdout = r.teeLog(r.b.Stdout())
+	run.Stderr = r.teeLog(r.b.Stderr())
 	run.Dir = r.def.WorkingDir
 
-	err := run.Run()
+	err := r.run(run)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrorExecutionFailed, err)
 	}
@@ -231,19 +386,22 @@ func (r *Exec) runWithTransform(cmd string, args []string, env []string) error {
 		return fmt.Errorf("%s: dry run mode", ErrorExecutionFailed)
 	}
 
+	out := bytes.NewBuffer([]byte{})
+
 	run := exec.CommandContext(r.ctx, cmd, args...)
-	run.Env = append(os.Environ(), env...)
+	run.Env = append(r.baseEnvironment(), env...)
 
 	run.Stdin = os.Stdin
-	run.Stderr = r.b.Stderr()
+	run.Stdout = r.teeLog(out)
+	run.Stderr = r.teeLog(r.b.Stderr())
 	run.Dir = r.def.WorkingDir
 
-	out, err := run.Output()
+	err := r.run(run)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrorExecutionFailed, err)
 	}
 
-	tRes, err := r.def.Transform.TransformBytes(r.ctx, out, r.arguments, r.flags, r.b)
+	tRes, err := r.def.Transform.TransformBytes(r.ctx, out.Bytes(), r.arguments, r.flags, r.b)
 	if err != nil {
 		return err
 	}
@@ -277,86 +435,148 @@ func (r *Exec) findShell() []string {
 	return []string{"/bin/sh", "-c"}
 }
 
-func (r *Exec) templateFuncs() template.FuncMap {
+func (r *Exec) templateFuncs(host string) template.FuncMap {
 	return template.FuncMap{
 		"BashHelperPath": func() string {
 			return r.helperPath
 		},
+		"ShHelperPath": func() string {
+			return r.helperFile(shHelperName)
+		},
+		"PythonHelperPath": func() string {
+			return r.helperFile(pythonHelperName)
+		},
+		"Host": func() string {
+			return host
+		},
+		"CommandName": func() string {
+			return r.def.Name
+		},
+		"Timestamp": func() string {
+			return r.started.Format(logTimestampFormat)
+		},
 	}
 }
 
-func (r *Exec) runCommand(_ *fisk.ParseContext) error {
-	var cmd string
-	var err error
-	var parts []string
+// templates are all the templates in the definition keyed by the property they are defined in
+func (r *Exec) templates() map[string]string {
+	res := map[string]string{}
 
-	if !r.def.NoHelper {
-		tf, err := os.CreateTemp(r.userDir, "appbuilder-*")
-		if err != nil {
-			return fmt.Errorf("%w: %v", ErrorHelperFailed, err)
+	add := func(property string, body string) {
+		if body != "" {
+			res[property] = body
 		}
-		defer os.Remove(tf.Name())
+	}
 
-		_, err = tf.Write(bashHelper)
-		if err != nil {
-			return fmt.Errorf("%w: %v", ErrorHelperFailed, err)
+	add("command", r.def.Command)
+	add("script", r.def.Script)
+	add("dir", r.def.WorkingDir)
+
+	for i, e := range r.def.Environment {
+		add(fmt.Sprintf("environment[%d]", i), e)
+	}
+
+	if r.def.Hosts != nil {
+		for i, h := range r.def.Hosts.List {
+			add(fmt.Sprintf("hosts.list[%d]", i), h)
 		}
-		tf.Close()
-		r.helperPath = tf.Name()
+		add("hosts.command", r.def.Hosts.Command)
 	}
 
+	if r.def.LogFile != nil {
+		add("log_file.path", r.def.LogFile.Path)
+	}
+
+	return res
+}
+
+func (r *Exec) render(body string, host string) (string, error) {
+	return r.b.RenderTemplate(body, r.arguments, r.flags, builder.WithSprig(), builder.WithFuncs(r.templateFuncs(host)))
+}
+
+// commandParts renders the command or script for host, host is empty when not running across hosts
+func (r *Exec) commandParts(host string) ([]string, error) {
+	var parts []string
+
 	if r.def.Command != "" {
-		cmd, err = r.b.RenderTemplate(r.def.Command, r.arguments, r.flags, builder.WithSprig(), builder.WithFuncs(r.templateFuncs()))
+		cmd, err := r.render(r.def.Command, host)
 		if err != nil {
-			return fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
+			return nil, fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
 		}
 
 		parts, err = shellquote.Split(cmd)
 		if err != nil {
-			return fmt.Errorf("%w: %v", ErrorInvalidCommand, err)
+			return nil, fmt.Errorf("%w: %v", ErrorInvalidCommand, err)
 		}
 	} else {
-		shell := r.findShell()
-		if len(shell) == 0 {
-			return fmt.Errorf("cannot determine shell, set SHELL or shell property")
+		script, err := r.render(r.def.Script, host)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
 		}
 
-		script, err := r.b.RenderTemplate(r.def.Script, r.arguments, r.flags, builder.WithSprig(), builder.WithFuncs(r.templateFuncs()))
+		interpreter, err := r.scriptInterpreter()
 		if err != nil {
-			return fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
+			return nil, err
 		}
 
-		parts = append(shell, script)
-	}
+		if len(interpreter) > 0 {
+			file, err := r.writeScript(script)
+			if err != nil {
+				return nil, fmt.Errorf("%w: could not save script: %v", ErrorInvalidCommand, err)
+			}
 
-	if r.def.WorkingDir != "" {
-		r.def.WorkingDir, err = r.b.RenderTemplate(r.def.WorkingDir, r.arguments, r.flags, builder.WithSprig(), builder.WithFuncs(r.templateFuncs()))
-		if err != nil {
-			return err
+			return append(interpreter, file), nil
 		}
-		r.log.Debugf("Running command in directory %s", r.def.WorkingDir)
+
+		shell := r.findShell()
+		if len(shell) == 0 {
+			return nil, fmt.Errorf("cannot determine shell, set SHELL or shell property")
+		}
+
+		parts = append(shell, script)
 	}
 
 	if len(parts) == 0 {
-		return ErrorInvalidCommand
+		return nil, ErrorInvalidCommand
 	}
 
+	return parts, nil
+}
+
+// environment renders the configured environment for host, host is empty when not running across hosts
+func (r *Exec) environment(host string) ([]string, error) {
 	var env []string
+
 	for _, e := range r.def.Environment {
-		v, err := r.b.RenderTemplate(e, r.arguments, r.flags, builder.WithSprig(), builder.WithFuncs(r.templateFuncs()))
+		v, err := r.render(e, host)
 		if err != nil {
-			return fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
+			return nil, fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
 		}
 		env = append(env, v)
 	}
 
+	if host != "" {
+		env = append(env, fmt.Sprintf("BUILDER_HOST=%s", host))
+	}
+
+	// makes 'import ab_helpers' work in python scripts
+	interpreter, _ := r.scriptInterpreter()
+	if isPython(interpreter) && r.helperFile(pythonHelperName) != "" {
+		path := r.tempDir
+		if pp := os.Getenv("PYTHONPATH"); pp != "" {
+			path = path + string(os.PathListSeparator) + pp
+		}
+		env = append(env, fmt.Sprintf("PYTHONPATH=%s", path))
+	}
+
+	return env, nil
+}
+
+// withRetries calls cb until it succeeds or the backoff policy is exhausted, cb receives the attempt number starting at 1
+func (r *Exec) withRetries(cb func(try int) error) error {
 	try := 1
 	for {
-		if r.def.Transform == nil {
-			err = r.runInTerminal(parts[0], parts[1:], append(env, fmt.Sprintf("BUILDER_TRY=%d", try)))
-		} else {
-			err = r.runWithTransform(parts[0], parts[1:], append(env, fmt.Sprintf("BUILDER_TRY=%d", try)))
-		}
+		err := cb(try)
 
 		// if it was good or we dont have backoff just return whatever is there
 		if err == nil || r.def.Backoff == nil {
@@ -383,3 +603,67 @@ func (r *Exec) runCommand(_ *fisk.ParseContext) error {
 		try++
 	}
 }
+
+func (r *Exec) runCommand(_ *fisk.ParseContext) error {
+	r.started = time.Now()
+
+	interpreter, err := r.scriptInterpreter()
+	if err != nil {
+		return err
+	}
+
+	if !r.def.NoHelper || len(interpreter) > 0 {
+		r.tempDir, err = os.MkdirTemp(r.userDir, "appbuilder-*")
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrorHelperFailed, err)
+		}
+		defer os.RemoveAll(r.tempDir)
+	}
+
+	if !r.def.NoHelper {
+		err = r.writeHelpers()
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.def.LogFile != nil {
+		lf, err := r.openLogFile()
+		if err != nil {
+			return fmt.Errorf("%w: could not open log file: %v", ErrorExecutionFailed, err)
+		}
+		defer lf.Close()
+
+		r.logOutput = &syncWriter{w: lf}
+	}
+
+	if r.def.WorkingDir != "" {
+		r.def.WorkingDir, err = r.render(r.def.WorkingDir, "")
+		if err != nil {
+			return err
+		}
+		r.log.Debugf("Running command in directory %s", r.def.WorkingDir)
+	}
+
+	if r.def.Hosts != nil {
+		return r.runOnHosts()
+	}
+
+	parts, err := r.commandParts("")
+	if err != nil {
+		return err
+	}
+
+	env, err := r.environment("")
+	if err != nil {
+		return err
+	}
+
+	return r.withRetries(func(try int) error {
+		if r.def.Transform == nil {
+			return r.runInTerminal(parts[0], parts[1:], append(env, fmt.Sprintf("BUILDER_TRY=%d", try)))
+		}
+
+		return r.runWithTransform(parts[0], parts[1:], append(env, fmt.Sprintf("BUILDER_TRY=%d", try)))
+	})
+}
@@ -5,8 +5,18 @@
 package exec
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/choria-io/appbuilder/builder"
 	"github.com/choria-io/fisk"
@@ -14,6 +24,16 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+func TestMain(m *testing.M) {
+	HandleLimitsWrapper()
+
+	if terminalHelper() {
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
 func TestExec(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "ExecCommand")
@@ -79,6 +99,20 @@ var _ = Describe("Exec", func() {
 			err := p.Validate(nil)
 			Expect(err).To(MatchError("only one of command or script is allowed"))
 		})
+
+		It("Should check templates", func() {
+			p.def.Name = "x"
+			p.def.Description = "x"
+			p.def.Flags = []builder.GenericFlag{{Name: "verbose"}}
+			p.def.Script = `. "{{ BashHelperPath }}"; echo {{ .Flags.verbose | upper }} {{ .Flags.verbos }} {{ Host }}`
+			p.def.Environment = []string{"X={{ missing }}"}
+
+			Expect(p.Validate(nil)).To(Succeed())
+			Expect(p.CheckTemplates()).To(Equal([]string{
+				`invalid template in environment[0]: template: choria:1: function "missing" not defined`,
+				`script references undeclared flag "verbos"`,
+			}))
+		})
 	})
 
 	Describe("findShell", func() {
@@ -106,4 +140,301 @@ var _ = Describe("Exec", func() {
 			Expect(p.findShell()).To(HaveLen(2))
 		})
 	})
+	Describe("Hosts", func() {
+		var out *bytes.Buffer
+
+		BeforeEach(func() {
+			out = bytes.NewBuffer([]byte{})
+			b, err := builder.New(context.Background(), "ginkgo", builder.WithStdout(out), builder.WithStderr(out), builder.WithLogger(builder.NoopLogger{}))
+			Expect(err).ToNot(HaveOccurred())
+
+			p = &Exec{def: &Command{}, b: b, ctx: context.Background(), log: builder.NoopLogger{}}
+			p.def.Type = "exec"
+			p.def.Command = "/bin/echo {{ Host }}"
+		})
+
+		It("Should validate the hosts", func() {
+			Expect((&Hosts{}).Validate()).To(MatchError("hosts requires a list or a command"))
+			Expect((&Hosts{List: []string{"a"}, Command: "x"}).Validate()).To(MatchError("hosts accepts only one of list or command"))
+			Expect((&Hosts{List: []string{"a"}, Parallel: -1}).Validate()).To(MatchError("hosts parallel can not be negative"))
+			Expect((&Hosts{List: []string{"a"}, Parallel: 2}).Validate()).To(Succeed())
+		})
+
+		It("Should run the command for every host with prefixed output", func() {
+			p.def.Hosts = &Hosts{List: []string{"one", "two"}, Parallel: 2}
+			Expect(p.runOnHosts()).To(Succeed())
+			Expect(out.String()).To(SatisfyAll(
+				ContainSubstring("one: one\n"),
+				ContainSubstring("two: two\n"),
+			))
+		})
+
+		It("Should discover hosts using a command", func() {
+			p.def.Shell = "/bin/sh"
+			p.def.Hosts = &Hosts{Command: "printf 'one\\n# comment\\n\\ntwo\\n'"}
+			hosts, err := p.resolveHosts()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hosts).To(Equal([]string{"one", "two"}))
+		})
+
+		It("Should pass results to the transform", func() {
+			p.def.Command = "/bin/sh -c 'echo {{ Host }}; test {{ Host }} = one'"
+			p.def.Hosts = &Hosts{List: []string{"one", "two"}}
+			p.def.Transform = &builder.Transform{Query: `.[] | "\(.host)=\(.exit_code)"`}
+
+			Expect(p.runOnHosts()).To(MatchError("execution failed: 1 of 2 hosts failed"))
+			Expect(out.String()).To(SatisfyAll(
+				ContainSubstring("one=0"),
+				ContainSubstring("two=1"),
+				Not(ContainSubstring("one: one")),
+			))
+		})
+
+		It("Should stop scheduling hosts when interrupted and report retry failures", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ran := filepath.Join(GinkgoT().TempDir(), "ran")
+			bo, err := newPolicy(2, 10*time.Second, 10*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+
+			p.ctx = ctx
+			p.bo = bo
+			p.def.Backoff = &Backoff{MaxAttempts: 2}
+			p.def.Command = fmt.Sprintf("/bin/sh -c 'echo {{ Host }} >> %s; exit 1'", ran)
+			p.def.Hosts = &Hosts{List: []string{"one", "two", "three"}}
+			p.def.Transform = &builder.Transform{Query: `.[] | "\(.host)=\(.error)"`}
+
+			go func() {
+				defer GinkgoRecover()
+				// interrupts the backoff sleep after the first try on the first host
+				Eventually(func() ([]byte, error) { return os.ReadFile(ran) }).ShouldNot(BeEmpty())
+				cancel()
+			}()
+
+			Expect(p.runOnHosts()).To(MatchError("execution failed: 3 of 3 hosts failed"))
+			Expect(os.ReadFile(ran)).To(Equal([]byte("one\n")))
+			Expect(out.String()).To(SatisfyAll(
+				ContainSubstring("one=context canceled"),
+				ContainSubstring("two=not run: context canceled"),
+				ContainSubstring("three=not run: context canceled"),
+			))
+		})
+	})
+
+	Describe("prefixWriter", func() {
+		It("Should prefix complete lines and flush partial ones", func() {
+			out := bytes.NewBuffer([]byte{})
+			w := &prefixWriter{mu: &sync.Mutex{}, w: out, prefix: "h: "}
+			w.Write([]byte("hello\nwor"))
+			Expect(out.String()).To(Equal("h: hello\n"))
+			w.Write([]byte("ld"))
+			w.Flush()
+			Expect(out.String()).To(Equal("h: hello\nh: world\n"))
+		})
+	})
+	Describe("Limits", func() {
+		It("Should validate the limits", func() {
+			nice := 30
+			l := &Limits{CPUTime: "500ms", AddressSpace: "lots", Nice: &nice}
+			Expect(l.Validate()).To(MatchError(`cpu_time limit must be at least 1s, invalid address_space limit: strconv.ParseFloat: parsing "": invalid syntax, nice must be between -20 and 19`))
+
+			nice = 10
+			l = &Limits{CPUTime: "1m", AddressSpace: "2GiB", OpenFiles: 100, Nice: &nice}
+			rl, err := l.parse()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rl.cpuSeconds).To(Equal(uint64(60)))
+			Expect(rl.addressSpace).To(Equal(uint64(2 * 1024 * 1024 * 1024)))
+			Expect(rl.openFiles).To(Equal(uint64(100)))
+		})
+
+		It("Should require clean_environment for environment_allow", func() {
+			p.def.Name = "x"
+			p.def.Description = "x"
+			p.def.Command = "x"
+			p.def.EnvironmentAllow = []string{"PATH"}
+			Expect(p.Validate(nil)).To(MatchError("environment_allow requires clean_environment"))
+		})
+
+		It("Should apply limits to the command", func() {
+			if runtime.GOOS != "linux" {
+				Skip("limits are only supported on linux")
+			}
+
+			out := bytes.NewBuffer([]byte{})
+			b, err := builder.New(context.Background(), "ginkgo", builder.WithStdout(out), builder.WithStderr(out), builder.WithLogger(builder.NoopLogger{}))
+			Expect(err).ToNot(HaveOccurred())
+
+			p = &Exec{def: &Command{}, b: b, ctx: context.Background(), log: builder.NoopLogger{}}
+			p.def.Limits = &Limits{OpenFiles: 64}
+
+			Expect(p.runInTerminal("/bin/sh", []string{"-c", "ulimit -n"}, nil)).To(Succeed())
+			Expect(strings.TrimSpace(out.String())).To(Equal("64"))
+		})
+	})
+
+	Describe("baseEnvironment", func() {
+		It("Should only pass allowed variables in a clean environment", func() {
+			os.Setenv("GINKGO_ALLOWED", "1")
+			os.Setenv("GINKGO_DENIED", "1")
+			defer os.Unsetenv("GINKGO_ALLOWED")
+			defer os.Unsetenv("GINKGO_DENIED")
+
+			Expect(p.baseEnvironment()).To(ContainElements("GINKGO_ALLOWED=1", "GINKGO_DENIED=1"))
+
+			p.def.CleanEnvironment = true
+			Expect(p.baseEnvironment()).To(BeEmpty())
+
+			p.def.EnvironmentAllow = []string{"GINKGO_A*"}
+			Expect(p.baseEnvironment()).To(Equal([]string{"GINKGO_ALLOWED=1"}))
+		})
+	})
+	Describe("Process groups", func() {
+		It("Should terminate the entire process group on cancellation", func() {
+			if runtime.GOOS != "linux" {
+				Skip("process state is checked using /proc")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			b, err := builder.New(ctx, "ginkgo", builder.WithLogger(builder.NoopLogger{}))
+			Expect(err).ToNot(HaveOccurred())
+
+			pidFile := filepath.Join(GinkgoT().TempDir(), "pid")
+			p = &Exec{def: &Command{}, b: b, ctx: ctx, log: builder.NoopLogger{}, grace: 200 * time.Millisecond}
+
+			go func() {
+				defer GinkgoRecover()
+				// the shell creates the file before writing the pid so wait for content
+				Eventually(func() (string, error) { pb, err := os.ReadFile(pidFile); return strings.TrimSpace(string(pb)), err }).ShouldNot(BeEmpty())
+				cancel()
+			}()
+
+			// the grandchild ignores SIGTERM so only the SIGKILL after the grace period stops it
+			err = p.run(exec.CommandContext(ctx, "/bin/sh", "-c", fmt.Sprintf("sh -c 'trap \"\" TERM; sleep 30' & echo $! > %s; wait", pidFile)))
+			Expect(err).To(HaveOccurred())
+
+			pb, err := os.ReadFile(pidFile)
+			Expect(err).ToNot(HaveOccurred())
+			pid, err := strconv.Atoi(strings.TrimSpace(string(pb)))
+			Expect(err).ToNot(HaveOccurred())
+
+			// the killed process might linger as a zombie until its new parent reaps it
+			Eventually(func() string {
+				stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+				if err != nil {
+					return "gone"
+				}
+				return strings.Fields(string(stat))[2]
+			}).Should(Or(Equal("gone"), Equal("Z")))
+		})
+	})
+	Describe("Interpreters", func() {
+		var out *bytes.Buffer
+
+		BeforeEach(func() {
+			out = bytes.NewBuffer([]byte{})
+			b, err := builder.New(context.Background(), "ginkgo", builder.WithStdout(out), builder.WithStderr(out), builder.WithLogger(builder.NoopLogger{}))
+			Expect(err).ToNot(HaveOccurred())
+
+			p = &Exec{def: &Command{}, b: b, ctx: context.Background(), log: builder.NoopLogger{}, userDir: GinkgoT().TempDir()}
+			p.def.Type = "exec"
+			p.def.Name = "ginkgo"
+			p.def.Description = "ginkgo"
+		})
+
+		It("Should validate interpreter settings", func() {
+			p.def.Command = "x"
+			p.def.Interpreter = "python3"
+			p.def.Shell = "/bin/sh"
+			Expect(p.Validate(nil)).To(MatchError("interpreter requires a script, only one of interpreter or shell is allowed"))
+
+			p.def.Command = ""
+			p.def.Script = "echo hello"
+			p.def.Shebang = true
+			Expect(p.Validate(nil)).To(MatchError("only one of interpreter or shell is allowed, shebang requires a script starting with a #! line"))
+
+			p.def.Script = "#!/bin/sh\necho hello"
+			Expect(p.Validate(nil)).To(MatchError("only one of interpreter or shell is allowed, shebang can not be combined with interpreter or shell"))
+		})
+
+		It("Should detect the interpreter", func() {
+			p.def.Script = "echo hello"
+			Expect(p.scriptInterpreter()).To(BeNil())
+
+			p.def.Script = "#!/usr/bin/env python3\nprint('hello')"
+			Expect(p.scriptInterpreter()).To(BeNil())
+
+			p.def.Shebang = true
+			Expect(p.scriptInterpreter()).To(Equal([]string{"/usr/bin/env", "python3"}))
+
+			p.def.Interpreter = "/bin/sh -e"
+			Expect(p.scriptInterpreter()).To(Equal([]string{"/bin/sh", "-e"}))
+		})
+
+		It("Should detect python interpreters", func() {
+			Expect(isPython([]string{"python3"})).To(BeTrue())
+			Expect(isPython([]string{"/usr/bin/env", "-S", "python3", "-u"})).To(BeTrue())
+			Expect(isPython([]string{"/bin/sh"})).To(BeFalse())
+			Expect(isPython([]string{"/usr/bin/env", "node"})).To(BeFalse())
+		})
+
+		It("Should run scripts with the sh helper", func() {
+			p.def.Interpreter = "/bin/sh"
+			p.def.Script = ". \"{{ ShHelperPath }}\"\nab_announce hello world"
+
+			Expect(p.runCommand(nil)).To(Succeed())
+			Expect(out.String()).To(Equal(">>>\n>>> hello world\n>>>\n"))
+		})
+
+		It("Should run python scripts with the python helper", func() {
+			_, err := exec.LookPath("python3")
+			if err != nil {
+				Skip("python3 is not installed")
+			}
+
+			p.def.Shebang = true
+			p.def.Script = "#!/usr/bin/env python3\nimport ab_helpers\nab_helpers.ab_say('hello', '{{ Host }}world')"
+
+			Expect(p.runCommand(nil)).To(Succeed())
+			Expect(out.String()).To(Equal(">>> hello world\n"))
+		})
+	})
+	Describe("LogFile", func() {
+		It("Should validate the log file", func() {
+			Expect((&LogFile{Keep: -1}).Validate()).To(MatchError("log_file requires a path, log_file keep can not be negative"))
+			Expect((&LogFile{Path: "/tmp/x.log", Keep: 1}).Validate()).To(MatchError("log_file keep requires {{ Timestamp }} in the path"))
+			Expect((&LogFile{Path: "/tmp/x-{{ Timestamp }}.log", Keep: 1}).Validate()).To(Succeed())
+		})
+
+		It("Should write output to the log and remove old logs", func() {
+			td := GinkgoT().TempDir()
+			out := bytes.NewBuffer([]byte{})
+			b, err := builder.New(context.Background(), "ginkgo", builder.WithStdout(out), builder.WithStderr(out), builder.WithLogger(builder.NoopLogger{}))
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, ts := range []string{"20200101-000000", "20210101-000000", "20220101-000000"} {
+				Expect(os.WriteFile(filepath.Join(td, fmt.Sprintf("ginkgo-%s.log", ts)), []byte("old"), 0600)).To(Succeed())
+			}
+
+			p = &Exec{def: &Command{}, b: b, ctx: context.Background(), log: builder.NoopLogger{}, userDir: td}
+			p.def.Name = "ginkgo"
+			p.def.NoHelper = true
+			p.def.Command = "/bin/echo hello world"
+			p.def.LogFile = &LogFile{Path: filepath.Join(td, "{{ CommandName }}-{{ Timestamp }}.log"), Keep: 2}
+
+			Expect(p.runCommand(nil)).To(Succeed())
+			Expect(out.String()).To(Equal("hello world\n"))
+
+			logs, err := filepath.Glob(filepath.Join(td, "ginkgo-*.log"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(HaveLen(2))
+			Expect(filepath.Base(logs[0])).To(Equal("ginkgo-20220101-000000.log"))
+
+			log, err := os.ReadFile(logs[1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(log)).To(Equal("hello world\n"))
+		})
+	})
 })
@@ -0,0 +1,302 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hosts runs the command once for every host, the current host is available to templates as {{ Host }}
+// and to the command as BUILDER_HOST
+type Hosts struct {
+	// List is a list of hosts to run the command for, each entry is templated
+	List []string `json:"list"`
+	// Command is a command producing the hosts to run the command for, one per line
+	Command string `json:"command"`
+	// Parallel is the maximum amount of hosts to run the command on concurrently, defaults to 1
+	Parallel int `json:"parallel"`
+}
+
+// hostResult is the result for a single host, a list of these is passed to any transform
+type hostResult struct {
+	Host     string  `json:"host"`
+	ExitCode int     `json:"exit_code"`
+	Duration float64 `json:"duration"`
+	Stdout   string  `json:"stdout"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Validate ensures the hosts definition is valid
+func (h *Hosts) Validate() error {
+	var errs []string
+
+	if len(h.List) == 0 && h.Command == "" {
+		errs = append(errs, "hosts requires a list or a command")
+	}
+
+	if len(h.List) > 0 && h.Command != "" {
+		errs = append(errs, "hosts accepts only one of list or command")
+	}
+
+	if h.Parallel < 0 {
+		errs = append(errs, "hosts parallel can not be negative")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// prefixWriter writes complete lines to w with prefix, partial lines are held until completed or flushed
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+
+	for {
+		idx := bytes.IndexByte(p.buf, '\n')
+		if idx == -1 {
+			break
+		}
+
+		p.writeLine(p.buf[:idx+1])
+		p.buf = p.buf[idx+1:]
+	}
+
+	return len(b), nil
+}
+
+// Flush writes any partial line that was not terminated by a new line
+func (p *prefixWriter) Flush() {
+	if len(p.buf) == 0 {
+		return
+	}
+
+	p.writeLine(append(p.buf, '\n'))
+	p.buf = nil
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	p.mu.Unlock()
+}
+
+func (r *Exec) resolveHosts() ([]string, error) {
+	var hosts []string
+
+	for _, h := range r.def.Hosts.List {
+		host, err := r.render(h, "")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
+		}
+
+		hosts = append(hosts, strings.TrimSpace(host))
+	}
+
+	if r.def.Hosts.Command != "" {
+		shell := r.findShell()
+		if len(shell) == 0 {
+			return nil, fmt.Errorf("cannot determine shell, set SHELL or shell property")
+		}
+
+		cmd, err := r.render(r.def.Hosts.Command, "")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrorTemplateFailed, err)
+		}
+
+		r.log.Debugf("Discovering hosts using %q", r.b.Secrets().Redact(cmd))
+
+		run := exec.CommandContext(r.ctx, shell[0], append(shell[1:], cmd)...)
+		run.Stderr = r.b.Stderr()
+		run.Dir = r.def.WorkingDir
+
+		out, err := run.Output()
+		if err != nil {
+			return nil, fmt.Errorf("%w: host discovery failed: %v", ErrorExecutionFailed, err)
+		}
+
+		for _, line := range strings.Split(string(out), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			hosts = append(hosts, line)
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%w: no hosts found", ErrorInvalidCommand)
+	}
+
+	return hosts, nil
+}
+
+func (r *Exec) runOnHost(mu *sync.Mutex, host string, parts []string, env []string) (*hostResult, error) {
+	res := &hostResult{Host: host, ExitCode: -1}
+	stdout := bytes.NewBuffer([]byte{})
+
+	run := exec.CommandContext(r.ctx, parts[0], parts[1:]...)
+	run.Env = append(r.baseEnvironment(), env...)
+	run.Dir = r.def.WorkingDir
+
+	outPrefix := &prefixWriter{mu: mu, w: r.teeLog(r.b.Stdout()), prefix: host + ": "}
+	errPrefix := &prefixWriter{mu: mu, w: r.teeLog(r.b.Stderr()), prefix: host + ": "}
+	defer errPrefix.Flush()
+
+	if r.def.Transform == nil {
+		defer outPrefix.Flush()
+		run.Stdout = io.MultiWriter(stdout, outPrefix)
+	} else {
+		logPrefix := &prefixWriter{mu: mu, w: r.teeLog(io.Discard), prefix: host + ": "}
+		defer logPrefix.Flush()
+		run.Stdout = io.MultiWriter(stdout, logPrefix)
+	}
+	run.Stderr = errPrefix
+
+	start := time.Now()
+	err := r.run(run)
+	res.Duration = time.Since(start).Seconds()
+	res.Stdout = stdout.String()
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			res.ExitCode = exitErr.ExitCode()
+		}
+		res.Error = err.Error()
+
+		return res, fmt.Errorf("%w: %s: %v", ErrorExecutionFailed, host, err)
+	}
+
+	res.ExitCode = 0
+
+	return res, nil
+}
+
+func (r *Exec) runOnHosts() error {
+	hosts, err := r.resolveHosts()
+	if err != nil {
+		return err
+	}
+
+	parts := make([][]string, len(hosts))
+	envs := make([][]string, len(hosts))
+
+	for i, host := range hosts {
+		parts[i], err = r.commandParts(host)
+		if err != nil {
+			return err
+		}
+
+		envs[i], err = r.environment(host)
+		if err != nil {
+			return err
+		}
+
+		r.logCommand(parts[i][0], parts[i][1:], envs[i])
+	}
+
+	if os.Getenv("BUILDER_DRY_RUN") != "" {
+		return fmt.Errorf("%s: dry run mode", ErrorExecutionFailed)
+	}
+
+	parallel := r.def.Hosts.Parallel
+	if parallel == 0 {
+		parallel = 1
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make([]*hostResult, len(hosts))
+		limit   = make(chan struct{}, parallel)
+	)
+
+	for i, host := range hosts {
+		// hosts not started once interrupted are reported as not run
+		select {
+		case limit <- struct{}{}:
+		case <-r.ctx.Done():
+		}
+		if r.ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			err := r.withRetries(func(try int) error {
+				var err error
+				results[i], err = r.runOnHost(&mu, host, parts[i], append(envs[i], fmt.Sprintf("BUILDER_TRY=%d", try)))
+				return err
+			})
+			if err != nil {
+				if results[i] == nil {
+					results[i] = &hostResult{Host: host, ExitCode: -1}
+				}
+				results[i].Error = err.Error()
+			}
+		}(i, host)
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for i, res := range results {
+		if res == nil {
+			res = &hostResult{Host: hosts[i], ExitCode: -1, Error: fmt.Sprintf("not run: %v", r.ctx.Err())}
+			results[i] = res
+		}
+
+		if res.ExitCode != 0 || res.Error != "" {
+			failed++
+		}
+	}
+
+	if r.def.Transform != nil {
+		j, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+
+		// still report the per host results when interrupted
+		tRes, err := r.def.Transform.TransformBytes(context.WithoutCancel(r.ctx), j, r.arguments, r.flags, r.b)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprint(r.b.Stdout(), string(tRes))
+		if err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d hosts failed", ErrorExecutionFailed, failed, len(hosts))
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package exec
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+var (
+	//go:embed sh_helpers.sh
+	shHelper []byte
+
+	//go:embed python_helpers.py
+	pythonHelper []byte
+)
+
+const (
+	bashHelperName   = "bash_helpers.sh"
+	shHelperName     = "sh_helpers.sh"
+	pythonHelperName = "ab_helpers.py"
+)
+
+// scriptInterpreter determines the interpreter for scripts that are executed from a file rather than
+// passed to a shell using -c, either set using interpreter or from the #! line in the script when shebang is set
+func (r *Exec) scriptInterpreter() ([]string, error) {
+	if r.def.Script == "" {
+		return nil, nil
+	}
+
+	if r.def.Interpreter != "" {
+		parts, err := shellquote.Split(r.def.Interpreter)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid interpreter: %v", ErrorInvalidCommand, err)
+		}
+
+		return parts, nil
+	}
+
+	if !r.def.Shebang || !strings.HasPrefix(r.def.Script, "#!") {
+		return nil, nil
+	}
+
+	line, _, _ := strings.Cut(strings.TrimPrefix(r.def.Script, "#!"), "\n")
+
+	parts := strings.Fields(line)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("%w: invalid #! line in script", ErrorInvalidCommand)
+	}
+
+	return parts, nil
+}
+
+// isPython determines if the interpreter is a python interpreter, used to expose the python helper as a module
+func isPython(interpreter []string) bool {
+	for _, part := range interpreter {
+		base := filepath.Base(part)
+		if strings.HasPrefix(base, "python") {
+			return true
+		}
+
+		// skip over env and its options in '/usr/bin/env -S python3 -u'
+		if base != "env" && !strings.HasPrefix(part, "-") {
+			return false
+		}
+	}
+
+	return false
+}
+
+// writeHelpers writes the helper libraries for all supported interpreters to the temporary directory
+func (r *Exec) writeHelpers() error {
+	helpers := map[string][]byte{
+		bashHelperName:   bashHelper,
+		shHelperName:     shHelper,
+		pythonHelperName: pythonHelper,
+	}
+
+	for name, helper := range helpers {
+		err := os.WriteFile(filepath.Join(r.tempDir, name), helper, 0600)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrorHelperFailed, err)
+		}
+	}
+
+	r.helperPath = filepath.Join(r.tempDir, bashHelperName)
+
+	return nil
+}
+
+// helperFile is the path to a helper library, empty when helpers are disabled
+func (r *Exec) helperFile(name string) string {
+	if r.def.NoHelper || r.tempDir == "" {
+		return ""
+	}
+
+	return filepath.Join(r.tempDir, name)
+}
+
+// writeScript saves the rendered script to the temporary directory so it can be passed to an interpreter
+func (r *Exec) writeScript(script string) (string, error) {
+	tf, err := os.CreateTemp(r.tempDir, "script-*")
+	if err != nil {
+		return "", err
+	}
+	defer tf.Close()
+
+	_, err = tf.WriteString(script)
+	if err != nil {
+		return "", err
+	}
+
+	return tf.Name(), nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Limits are resource limits applied to the executed command
+type Limits struct {
+	// CPUTime is the maximum CPU time the command may consume like 10m
+	CPUTime string `json:"cpu_time"`
+	// AddressSpace is the maximum size of the virtual memory of the command like 2GiB
+	AddressSpace string `json:"address_space"`
+	// OpenFiles is the maximum number of files the command may have open
+	OpenFiles uint64 `json:"open_files"`
+	// Nice is the scheduling priority to run the command at, -20 to 19
+	Nice *int `json:"nice"`
+}
+
+// resourceLimits are the parsed limits, zero values are not applied
+type resourceLimits struct {
+	cpuSeconds   uint64
+	addressSpace uint64
+	openFiles    uint64
+	nice         *int
+}
+
+// Validate ensures the limits are valid
+func (l *Limits) Validate() error {
+	_, err := l.parse()
+	return err
+}
+
+func (l *Limits) parse() (*resourceLimits, error) {
+	var errs []string
+
+	res := &resourceLimits{
+		openFiles: l.OpenFiles,
+		nice:      l.Nice,
+	}
+
+	if l.CPUTime != "" {
+		d, err := time.ParseDuration(l.CPUTime)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Sprintf("invalid cpu_time limit: %v", err))
+		case d < time.Second:
+			errs = append(errs, "cpu_time limit must be at least 1s")
+		default:
+			res.cpuSeconds = uint64(d.Seconds())
+		}
+	}
+
+	if l.AddressSpace != "" {
+		size, err := humanize.ParseBytes(l.AddressSpace)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid address_space limit: %v", err))
+		}
+		res.addressSpace = size
+	}
+
+	if l.Nice != nil && (*l.Nice < -20 || *l.Nice > 19) {
+		errs = append(errs, "nice must be between -20 and 19")
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, ", "))
+	}
+
+	return res, nil
+}
+
+// baseEnvironment is the environment commands start from before the configured environment is added,
+// when clean_environment is set only variables matching environment_allow are passed through
+func (r *Exec) baseEnvironment() []string {
+	if !r.def.CleanEnvironment {
+		return os.Environ()
+	}
+
+	var env []string
+	for _, e := range os.Environ() {
+		name, _, _ := strings.Cut(e, "=")
+
+		for _, allow := range r.def.EnvironmentAllow {
+			if ok, _ := filepath.Match(allow, name); ok {
+				env = append(env, e)
+				break
+			}
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// limitsEnvVar passes the limits to the copy of this program that applies them before running the command
+const limitsEnvVar = "_APPBUILDER_EXEC_LIMITS"
+
+// limitsWrapperHandled indicates this program calls HandleLimitsWrapper so it can be used to apply limits
+var limitsWrapperHandled bool
+
+// HandleLimitsWrapper replaces this program with the command it was started for when it is the copy applying
+// resource limits, it does not return in that case. Programs running commands with limits must call this at the
+// start of main, before doing any other work, commands with limits fail otherwise.
+func HandleLimitsWrapper() {
+	limitsWrapperHandled = true
+
+	spec, ok := os.LookupEnv(limitsEnvVar)
+	if !ok {
+		return
+	}
+
+	err := execWithLimits(spec, os.Args[1:])
+	fmt.Fprintf(os.Stderr, "could not run command with limits: %v\n", err)
+	os.Exit(126)
+}
+
+// wrap arranges for cmd to be started by a copy of this program that sets the limits on itself and then executes
+// the command, Go can not set limits between fork and exec so this ensures the command never runs without them
+func (l *Limits) wrap(cmd *exec.Cmd) error {
+	if cmd.Err != nil {
+		return nil
+	}
+
+	if !limitsWrapperHandled {
+		return fmt.Errorf("could not apply limits: the program does not call exec.HandleLimitsWrapper()")
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not apply limits: %w", err)
+	}
+
+	spec, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("could not apply limits: %w", err)
+	}
+
+	cmd.Env = append(cmd.Environ(), fmt.Sprintf("%s=%s", limitsEnvVar, spec))
+	cmd.Args = append([]string{cmd.Args[0], cmd.Path}, cmd.Args...)
+	cmd.Path = self
+
+	return nil
+}
+
+// execWithLimits applies the limits in spec to this process and replaces it with the command in args, the path to
+// run followed by its arguments
+func execWithLimits(spec string, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("no command given")
+	}
+
+	var l Limits
+	err := json.Unmarshal([]byte(spec), &l)
+	if err != nil {
+		return err
+	}
+
+	limits, err := l.parse()
+	if err != nil {
+		return err
+	}
+
+	var env []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, limitsEnvVar+"=") {
+			env = append(env, e)
+		}
+	}
+
+	err = limits.apply()
+	if err != nil {
+		return err
+	}
+
+	return syscall.Exec(args[0], args[1:], env)
+}
+
+// apply sets the limits on the current process, the address space is set last as it might be below what this
+// process already uses
+func (l *resourceLimits) apply() error {
+	set := func(resource int, name string, value uint64) error {
+		if value == 0 {
+			return nil
+		}
+
+		err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value})
+		if err != nil {
+			return fmt.Errorf("could not set %s limit: %w", name, err)
+		}
+
+		return nil
+	}
+
+	if l.nice != nil {
+		err := unix.Setpriority(unix.PRIO_PROCESS, 0, *l.nice)
+		if err != nil {
+			return fmt.Errorf("could not set nice: %w", err)
+		}
+	}
+
+	err := set(unix.RLIMIT_CPU, "cpu_time", l.cpuSeconds)
+	if err != nil {
+		return err
+	}
+
+	err = set(unix.RLIMIT_NOFILE, "open_files", l.openFiles)
+	if err != nil {
+		return err
+	}
+
+	return set(unix.RLIMIT_AS, "address_space", l.addressSpace)
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package exec
+
+import (
+	"os/exec"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limits wrapper", func() {
+	It("Should require the program to handle the limits wrapper", func() {
+		DeferCleanup(func() { limitsWrapperHandled = true })
+		limitsWrapperHandled = false
+
+		l := &Limits{OpenFiles: 64}
+		Expect(l.wrap(exec.Command("/bin/true"))).To(MatchError("could not apply limits: the program does not call exec.HandleLimitsWrapper()"))
+
+		limitsWrapperHandled = true
+		Expect(l.wrap(exec.Command("/bin/true"))).To(Succeed())
+	})
+})
@@ -0,0 +1,21 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package exec
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// HandleLimitsWrapper replaces this program with the command it was started for when it is the copy applying
+// resource limits, limits are not supported on this platform so it does nothing
+func HandleLimitsWrapper() {}
+
+func (l *Limits) wrap(_ *exec.Cmd) error {
+	return fmt.Errorf("resource limits are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/choria-io/appbuilder/builder"
+)
+
+// logTimestampFormat is the format of the {{ Timestamp }} template function
+const logTimestampFormat = "20060102-150405"
+
+// LogFile saves the output of every run to a file
+type LogFile struct {
+	// Path is the file to write, it is templated and should include {{ Timestamp }} to create a file per run
+	Path string `json:"path"`
+	// Keep is the number of log files to retain, older files matching the path are removed, 0 keeps all
+	Keep int `json:"keep"`
+}
+
+// Validate ensures the log file definition is valid
+func (l *LogFile) Validate() error {
+	var errs []string
+
+	if l.Path == "" {
+		errs = append(errs, "log_file requires a path")
+	}
+
+	if l.Keep < 0 {
+		errs = append(errs, "log_file keep can not be negative")
+	}
+
+	if l.Keep > 0 && !strings.Contains(l.Path, "Timestamp") {
+		errs = append(errs, "log_file keep requires {{ Timestamp }} in the path")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// syncWriter serializes writes to w
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.w.Write(p)
+}
+
+// renderLogPath renders the log file path, when glob is true the timestamp is a wildcard so previous runs can be found
+func (r *Exec) renderLogPath(glob bool) (string, error) {
+	funcs := template.FuncMap{}
+	if glob {
+		funcs["Timestamp"] = func() string { return "*" }
+	}
+
+	path, err := r.b.RenderTemplate(r.def.LogFile.Path, r.arguments, r.flags, builder.WithSprig(), builder.WithFuncs(r.templateFuncs("")), builder.WithFuncs(funcs))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid log_file path: %v", ErrorTemplateFailed, err)
+	}
+
+	return path, nil
+}
+
+// openLogFile creates the log file for this run and removes log files from previous runs beyond the retention count
+func (r *Exec) openLogFile() (*os.File, error) {
+	path, err := r.renderLogPath(false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.Debugf("Writing command output to %s", path)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.def.LogFile.Keep > 0 {
+		err = r.rotateLogFiles()
+		if err != nil {
+			r.log.Warnf("Could not remove old log files: %v", err)
+		}
+	}
+
+	return f, nil
+}
+
+func (r *Exec) rotateLogFiles() error {
+	pattern, err := r.renderLogPath(true)
+	if err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	if len(matches) <= r.def.LogFile.Keep {
+		return nil
+	}
+
+	// the timestamp format sorts in time order, newest last
+	sort.Strings(matches)
+
+	for _, old := range matches[:len(matches)-r.def.LogFile.Keep] {
+		r.log.Debugf("Removing old log file %s", old)
+		err = os.Remove(old)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
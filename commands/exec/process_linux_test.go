@@ -0,0 +1,126 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/appbuilder/builder"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+// terminalHelperEnv makes the test binary run terminalHelper instead of the tests, interactiveHelperEnv makes the
+// command it runs interactive
+const (
+	terminalHelperEnv    = "_APPBUILDER_TEST_TERMINAL_HELPER"
+	interactiveHelperEnv = "_APPBUILDER_TEST_TERMINAL_INTERACTIVE"
+)
+
+// terminalHelper runs a command reading from the terminal when the test binary was started as the session leader
+// of a pty by the tests, reports if it ran
+func terminalHelper() bool {
+	command := os.Getenv(terminalHelperEnv)
+	if command == "" {
+		return false
+	}
+
+	b, err := builder.New(context.Background(), "ginkgo", builder.WithLogger(builder.NoopLogger{}))
+	if err == nil {
+		p := &Exec{def: &Command{Interactive: os.Getenv(interactiveHelperEnv) != ""}, b: b, ctx: context.Background(), log: builder.NoopLogger{}}
+		err = p.runInTerminal("/bin/sh", []string{"-c", command}, nil)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return true
+}
+
+// openPty opens a new pty returning the controlling and terminal side
+func openPty() (*os.File, *os.File, error) {
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fd := int(ptmx.Fd())
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+
+	return ptmx, tty, nil
+}
+
+var _ = Describe("Terminal", func() {
+	DescribeTable("Should let commands read from the terminal", func(interactive string) {
+		ptmx, tty, err := openPty()
+		if err != nil {
+			Skip(fmt.Sprintf("pty not available: %v", err))
+		}
+		defer ptmx.Close()
+
+		helper := exec.Command(os.Args[0])
+		helper.Env = append(os.Environ(), terminalHelperEnv+"=read x; echo got $x", interactiveHelperEnv+"="+interactive)
+		helper.Stdin = tty
+		helper.Stdout = tty
+		helper.Stderr = tty
+		helper.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+		Expect(helper.Start()).To(Succeed())
+		tty.Close()
+		defer helper.Process.Kill()
+
+		var (
+			out bytes.Buffer
+			mu  sync.Mutex
+		)
+		output := func() string { mu.Lock(); defer mu.Unlock(); return out.String() }
+
+		go func() {
+			buf := make([]byte, 1024)
+			for {
+				n, err := ptmx.Read(buf)
+				mu.Lock()
+				out.Write(buf[:n])
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		_, err = ptmx.Write([]byte("hello\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(output, 5*time.Second).Should(ContainSubstring("got hello"))
+		Expect(helper.Wait()).To(Succeed())
+	},
+		Entry("by default", ""),
+		Entry("when interactive", "1"),
+	)
+})
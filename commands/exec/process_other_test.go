@@ -0,0 +1,12 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package exec
+
+// terminalHelper is only used on linux where the tests create a pty
+func terminalHelper() bool {
+	return false
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package exec
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup runs cmd in a new process group so signals reach every process it starts and reports if it did.
+// Commands reading from the terminal we are in the foreground of stay in our process group so they can use the
+// terminal as before, interactive ones get their own group that becomes the foreground group instead, Ctrl-C then
+// reaches them directly rather than through us. The returned function hands the terminal back once cmd completed
+func setProcessGroup(cmd *exec.Cmd, interactive bool) (func(), bool) {
+	tty, ok := cmd.Stdin.(*os.File)
+	if !ok {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		return func() {}, true
+	}
+
+	fd := int(tty.Fd())
+	fg, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	switch {
+	case err != nil || fg != unix.Getpgrp():
+		// not a terminal or not one we can read from
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		return func() {}, true
+
+	case !interactive:
+		return func() {}, false
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: true, Ctty: fd}
+
+	return func() {
+		// we are a background process until this completes, avoid being stopped for touching the terminal
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+
+		unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, unix.Getpgrp())
+	}, true
+}
+
+// signalProcess sends sig to the process group led by pid, or only to pid when it is not in its own group
+func signalProcess(pid int, group bool, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		s = syscall.SIGTERM
+	}
+
+	return syscall.Kill(groupPid(pid, group), s)
+}
+
+func processRunning(pid int, group bool) bool {
+	return syscall.Kill(groupPid(pid, group), 0) == nil
+}
+
+func killProcess(pid int, group bool) error {
+	return syscall.Kill(groupPid(pid, group), syscall.SIGKILL)
+}
+
+// groupPid is the pid used to signal the process group led by pid
+func groupPid(pid int, group bool) int {
+	if group {
+		return -pid
+	}
+
+	return pid
+}
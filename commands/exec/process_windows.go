@@ -0,0 +1,34 @@
+// Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package exec
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a noop, on windows only the direct child is terminated
+func setProcessGroup(_ *exec.Cmd, _ bool) (func(), bool) {
+	return func() {}, false
+}
+
+func signalProcess(pid int, _ bool, _ os.Signal) error {
+	return killProcess(pid, false)
+}
+
+func processRunning(_ int, _ bool) bool {
+	return false
+}
+
+func killProcess(pid int, _ bool) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return p.Kill()
+}
@@ -0,0 +1,53 @@
+# Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+#
+# SPDX-License-Identifier: Apache-2.0
+
+# Python version of bash_helpers.sh, use with 'import ab_helpers'
+
+import os
+import sys
+import time
+
+AB_SAY_PREFIX = ">>>"
+AB_ANNOUNCE_PREFIX = ">>>"
+AB_ERROR_PREFIX = "!!!"
+
+
+def ab_prefix(p):
+    stamp = os.environ.get("AB_HELPER_TIME_STAMP", "")
+
+    if stamp in ("T", "t", "1"):
+        return "[%s] %s" % (time.strftime("%H:%M:%S"), p)
+    elif stamp in ("D", "d", "2"):
+        return "[%s] %s" % (time.strftime("%Y-%m-%d %H:%M:%S"), p)
+
+    return p
+
+
+def _message(args):
+    return " ".join(str(a) for a in args)
+
+
+def ab_say(*args):
+    print("%s %s" % (ab_prefix(AB_SAY_PREFIX), _message(args)), flush=True)
+
+
+def ab_announce(*args):
+    p = ab_prefix(AB_ANNOUNCE_PREFIX)
+
+    print(p)
+    print("%s %s" % (p, _message(args)))
+    print(p, flush=True)
+
+
+def ab_error(*args):
+    p = ab_prefix(AB_ERROR_PREFIX)
+
+    print(p)
+    print("%s %s" % (p, _message(args)))
+    print(p, flush=True)
+
+
+def ab_panic(*args):
+    ab_error(*args)
+    sys.exit(1)
@@ -0,0 +1,48 @@
+# Copyright (c) 2026, R.I. Pienaar and the Choria Project contributors
+#
+# SPDX-License-Identifier: Apache-2.0
+
+# POSIX sh version of bash_helpers.sh for shells like dash and busybox
+
+AB_SAY_PREFIX=">>>"
+AB_ANNOUNCE_PREFIX=">>>"
+AB_ERROR_PREFIX="!!!"
+
+ab_prefix() {
+  case "$AB_HELPER_TIME_STAMP" in
+    T | t | 1)
+      echo "[$(date '+%T')] $1"
+      ;;
+    D | d | 2)
+      echo "[$(date '+%F %T')] $1"
+      ;;
+    *)
+      echo "$1"
+      ;;
+  esac
+}
+
+ab_say() {
+  echo "$(ab_prefix "${AB_SAY_PREFIX}") $*"
+}
+
+ab_announce() {
+  _ab_p=$(ab_prefix "${AB_ANNOUNCE_PREFIX}")
+
+  echo "${_ab_p}"
+  echo "${_ab_p} $*"
+  echo "${_ab_p}"
+}
+
+ab_error() {
+  _ab_p=$(ab_prefix "${AB_ERROR_PREFIX}")
+
+  echo "${_ab_p}"
+  echo "${_ab_p} $*"
+  echo "${_ab_p}"
+}
+
+ab_panic() {
+  ab_error "$*"
+  exit 1
+}
@@ -40,11 +40,11 @@ type Form struct {
 }
 
 func Register() error {
-	return builder.RegisterCommand("form", NewFormCommand)
+	return builder.RegisterCommand("form", NewFormCommand, &Command{})
 }
 
 func MustRegister() {
-	builder.MustRegisterCommand("form", NewFormCommand)
+	builder.MustRegisterCommand("form", NewFormCommand, &Command{})
 }
 
 func NewFormCommand(b *builder.AppBuilder, j json.RawMessage, log builder.Logger) (builder.Command, error) {
@@ -60,9 +60,9 @@ func NewFormCommand(b *builder.AppBuilder, j json.RawMessage, log builder.Logger
 		b:         b,
 	}
 
-	err := json.Unmarshal(j, form.def)
+	err := b.UnmarshalCommand(j, form.def)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", builder.ErrInvalidDefinition, err)
+		return nil, err
 	}
 
 	return form, nil
@@ -105,6 +105,23 @@ func (r *Form) Validate(log builder.Logger) error {
 	return nil
 }
 
+func (r *Form) CheckTemplates() []string {
+	// the entire definition is rendered before showing the form, sub commands are checked separately
+	var def map[string]any
+	err := json.Unmarshal(r.defBytes, &def)
+	if err != nil {
+		return nil
+	}
+	delete(def, "commands")
+
+	j, err := json.Marshal(def)
+	if err != nil {
+		return nil
+	}
+
+	return r.b.ValidateTemplates(&r.def.GenericCommand, nil, map[string]string{"definition": string(j)}, builder.WithSprig())
+}
+
 func (r *Form) SubCommands() []json.RawMessage {
 	return r.def.Commands
 }
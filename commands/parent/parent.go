@@ -8,16 +8,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/choria-io/appbuilder/builder"
 	"github.com/choria-io/fisk"
-	"github.com/goccy/go-yaml"
 )
 
 type Command struct {
-	IncludeFile string `json:"include_file"`
+	// IncludeFile loads the commands from other files, includes are expanded by the builder while loading definitions
+	IncludeFile builder.Includes `json:"include_file"`
 	builder.GenericSubCommands
 	builder.GenericCommand
 }
@@ -28,57 +27,26 @@ type Parent struct {
 }
 
 func Register() {
-	builder.RegisterCommand("parent", NewParentCommand)
+	builder.RegisterCommand("parent", NewParentCommand, &Command{})
 }
 
 func MustRegister() {
-	builder.MustRegisterCommand("parent", NewParentCommand)
+	builder.MustRegisterCommand("parent", NewParentCommand, &Command{})
 }
 
-func NewParentCommand(_ *builder.AppBuilder, j json.RawMessage, _ builder.Logger) (builder.Command, error) {
+func NewParentCommand(b *builder.AppBuilder, j json.RawMessage, _ builder.Logger) (builder.Command, error) {
 	parent := &Parent{
 		def: &Command{},
 	}
 
-	err := json.Unmarshal(j, parent.def)
+	err := b.UnmarshalCommand(j, parent.def)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", builder.ErrInvalidDefinition, err)
-	}
-
-	if parent.def.IncludeFile != "" {
-		err = parent.includeCommands()
-		if err != nil {
-			return nil, err
-		}
-		parent.def.IncludeFile = ""
+		return nil, err
 	}
 
 	return parent, nil
 }
 
-func (p *Parent) includeCommands() error {
-	b, err := os.ReadFile(p.def.IncludeFile)
-	if err != nil {
-		return err
-	}
-
-	name := p.def.Name
-
-	cfgj, err := yaml.YAMLToJSON(b)
-	if err != nil {
-		return err
-	}
-
-	err = json.Unmarshal(cfgj, p.def)
-	if err != nil {
-		return err
-	}
-
-	p.def.Name = name
-
-	return nil
-}
-
 func (p *Parent) String() string { return fmt.Sprintf("%s (parent)", p.def.Name) }
 
 func (p *Parent) Validate(log builder.Logger) error {
@@ -98,8 +66,8 @@ func (p *Parent) Validate(log builder.Logger) error {
 		errs = append(errs, err.Error())
 	}
 
-	if len(p.def.Flags) > 0 {
-		errs = append(errs, "parent commands can not have flags")
+	if p.def.Constraints != nil {
+		errs = append(errs, "parent commands can not have constraints")
 	}
 
 	if len(p.def.Arguments) > 0 {
@@ -129,6 +97,11 @@ func (p *Parent) SubCommands() []json.RawMessage {
 	return p.def.Commands
 }
 
+// InheritedFlags are the flags of the parent, they are added to all commands below it
+func (p *Parent) InheritedFlags() []builder.GenericFlag {
+	return p.def.Flags
+}
+
 func (p *Parent) CreateCommand(app builder.KingpinCommand) (*fisk.CmdClause, error) {
 	p.cmd = app.Command(p.def.Name, p.def.Description)
 	for _, a := range p.def.Aliases {
@@ -55,16 +55,21 @@ var _ = Describe("Parent", func() {
 			Expect(err).To(MatchError("parent requires sub commands"))
 		})
 
-		It("Should not allow flags, arguments or confirm", func() {
+		It("Should not allow constraints, arguments or confirm", func() {
 			p.def.GenericCommand.Name = "ginkgo"
 			p.def.GenericCommand.Description = "ginkgo description"
 			p.def.Commands = []json.RawMessage{[]byte("{}")}
 
-			p.def.Flags = []builder.GenericFlag{{}}
+			p.def.Flags = []builder.GenericFlag{{Name: "env", Description: "env"}}
 			err := p.Validate(nil)
-			Expect(err).To(MatchError("parent commands can not have flags"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.InheritedFlags()).To(Equal(p.def.Flags))
+
+			p.def.Constraints = &builder.GenericConstraints{}
+			err = p.Validate(nil)
+			Expect(err).To(MatchError("parent commands can not have constraints"))
 
-			p.def.Flags = nil
+			p.def.Constraints = nil
 			p.def.Arguments = []builder.GenericArgument{{}}
 			err = p.Validate(nil)
 			Expect(err).To(MatchError("parent commands can not have arguments"))
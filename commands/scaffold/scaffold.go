@@ -47,11 +47,11 @@ var (
 )
 
 func Register() error {
-	return builder.RegisterCommand("scaffold", NewScaffoldCommand)
+	return builder.RegisterCommand("scaffold", NewScaffoldCommand, &Command{})
 }
 
 func MustRegister() {
-	builder.MustRegisterCommand("scaffold", NewScaffoldCommand)
+	builder.MustRegisterCommand("scaffold", NewScaffoldCommand, &Command{})
 }
 
 func NewScaffoldCommand(b *builder.AppBuilder, j json.RawMessage, log builder.Logger) (builder.Command, error) {
@@ -66,9 +66,9 @@ func NewScaffoldCommand(b *builder.AppBuilder, j json.RawMessage, log builder.Lo
 		flags:     map[string]any{},
 	}
 
-	err := json.Unmarshal(j, s.def)
+	err := b.UnmarshalCommand(j, s.def)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", builder.ErrInvalidDefinition, err)
+		return nil, err
 	}
 
 	return s, nil
@@ -103,6 +103,10 @@ func (r *Scaffold) Validate(log builder.Logger) error {
 	return nil
 }
 
+func (r *Scaffold) CheckTemplates() []string {
+	return r.b.ValidateTemplates(&r.def.GenericCommand, nil, map[string]string{"source_directory": r.def.SourceDirectory, "target": r.def.Target}, builder.WithSprig())
+}
+
 func (r *Scaffold) SubCommands() []json.RawMessage {
 	return r.def.Commands
 }
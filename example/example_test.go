@@ -7,6 +7,7 @@ package example
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -20,8 +21,10 @@ import (
 	"github.com/choria-io/appbuilder/commands/parent"
 	"github.com/choria-io/appbuilder/commands/scaffold"
 	"github.com/choria-io/fisk"
+	"github.com/goccy/go-yaml"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 func TestBuilder(t *testing.T) {
@@ -276,4 +279,32 @@ var _ = Describe("Example Application", func() {
 			})
 		})
 	})
+	Describe("Schema", func() {
+		It("Should validate the examples", func() {
+			sj, err := json.Marshal(builder.Schema())
+			Expect(err).ToNot(HaveOccurred())
+			doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(sj))
+			Expect(err).ToNot(HaveOccurred())
+
+			compiler := jsonschema.NewCompiler()
+			Expect(compiler.AddResource("schema.json", doc)).To(Succeed())
+			schema, err := compiler.Compile("schema.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, f := range []string{"sample-app.yaml", "ABTaskFile", "include-app.yaml"} {
+				yb, err := os.ReadFile(f)
+				Expect(err).ToNot(HaveOccurred())
+				jb, err := yaml.YAMLToJSON(yb)
+				Expect(err).ToNot(HaveOccurred())
+				inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(jb))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(schema.Validate(inst)).To(Succeed(), f)
+			}
+
+			invalid, err := jsonschema.UnmarshalJSON(strings.NewReader(`{"commands":[{"name":"x","type":"exec","enviroment":["X=1"]}]}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema.Validate(invalid)).To(MatchError(ContainSubstring("enviroment")))
+		})
+	})
 })
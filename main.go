@@ -14,9 +14,12 @@ import (
 
 	"github.com/choria-io/appbuilder/builder"
 	"github.com/choria-io/appbuilder/commands"
+	"github.com/choria-io/appbuilder/commands/exec"
 )
 
 func main() {
+	exec.HandleLimitsWrapper()
+
 	name := filepath.Base(os.Args[0])
 
 	var err error